@@ -0,0 +1,368 @@
+// Package assembler encodes parsed LEGv8 programs into 32-bit machine code.
+package assembler
+
+import (
+	"fmt"
+	"strings"
+
+	"server/ast"
+	"server/isa"
+)
+
+// ErrorKind classifies the problems the assembler reports.
+type ErrorKind int8
+
+const (
+	// SyntaxError is an instruction that is malformed or uses an unknown register.
+	SyntaxError ErrorKind = iota
+	// RangeError is an immediate, branch offset or label address that does not
+	// fit its field.
+	RangeError
+	// LabelError is a branch to, or the address of, a label that is not defined.
+	LabelError
+)
+
+// Error is a problem that prevents an instruction from being encoded.
+type Error struct {
+	Kind    ErrorKind
+	Range   ast.Range
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Range.Start.Line+1, e.Range.Start.Column+1, e.Message)
+}
+
+// Word is a single encoded instruction.
+type Word struct {
+	Address  uint64
+	Encoding uint32
+
+	// Line is the zero-based source line of the instruction.
+	Line int
+}
+
+// Assembly is the machine code of an assembled program.
+type Assembly struct {
+	Words []Word
+}
+
+// Assemble encodes every instruction in a program. Words are produced for
+// every instruction that could be encoded, even when errors are reported.
+func Assemble(program *ast.Program) (*Assembly, []*Error) {
+	assembly := &Assembly{Words: []Word{}}
+	errs := []*Error{}
+
+	for _, instruction := range program.Instructions() {
+		if !instruction.Valid {
+			errs = append(errs, &Error{SyntaxError, instruction.Range, fmt.Sprintf("Cannot assemble malformed %s instruction.", instruction.Mnemonic)})
+			errs = append(errs, checkRanges(instruction)...)
+			continue
+		}
+
+		encodings, instructionErrs := encode(program, instruction)
+		errs = append(errs, instructionErrs...)
+		if len(instructionErrs) > 0 {
+			continue
+		}
+		for i, encoding := range encodings {
+			assembly.Words = append(assembly.Words, Word{
+				Address:  uint64(instruction.Index+i) * 4,
+				Encoding: encoding,
+				Line:     instruction.Range.Start.Line,
+			})
+		}
+	}
+
+	return assembly, errs
+}
+
+// operand is a single register, immediate or label operand, with memory
+// references and shifts flattened into their parts.
+type operand struct {
+	register  string
+	immediate *ast.Immediate
+	label     string
+	rng       ast.Range
+}
+
+func flatten(instruction *ast.Instruction) []operand {
+	operands := []operand{}
+	for _, o := range instruction.Operands {
+		switch o := o.(type) {
+		case *ast.Register:
+			operands = append(operands, operand{register: o.Name, rng: o.Range})
+		case *ast.Immediate:
+			operands = append(operands, operand{immediate: o, rng: o.Range})
+		case *ast.LabelRef:
+			operands = append(operands, operand{label: o.Name, rng: o.Range})
+		case *ast.MemoryRef:
+			operands = append(operands, operand{register: o.Base.Name, rng: o.Base.Range})
+			operands = append(operands, operand{immediate: o.Offset, rng: o.Offset.Range})
+		case *ast.Shift:
+			operands = append(operands, operand{immediate: o.Amount, rng: o.Amount.Range})
+		}
+	}
+	return operands
+}
+
+// machineInstruction is a mnemonic of isa.Instructions and its operands.
+type machineInstruction struct {
+	mnemonic string
+	operands []operand
+}
+
+// expand rewrites the operands of a pseudo-instruction into the instructions
+// it is assembled as, keeping the source range of each operand.
+func expand(pseudo *isa.Pseudo, operands []operand, whole ast.Range) []machineInstruction {
+	placeholders := make([]string, len(operands))
+	for i := range operands {
+		placeholders[i] = fmt.Sprintf("op%d", i)
+	}
+
+	instructions := []machineInstruction{}
+	for _, expansion := range pseudo.Expand(placeholders) {
+		space := strings.IndexByte(expansion, ' ')
+		expanded := []operand{}
+		for _, field := range strings.Split(expansion[space+1:], ", ") {
+			field = strings.TrimPrefix(field, "#")
+			var i int
+			if _, err := fmt.Sscanf(field, "op%d", &i); err == nil && i < len(operands) {
+				expanded = append(expanded, operands[i])
+				continue
+			}
+			expanded = append(expanded, operand{register: field, rng: whole})
+		}
+		instructions = append(instructions, machineInstruction{expansion[:space], expanded})
+	}
+	return instructions
+}
+
+// field is the inclusive range of values an operand can encode.
+type field struct {
+	min  int64
+	max  int64
+	name string
+}
+
+var (
+	aluImmediate  = field{0, 1<<12 - 1, "ALU_immediate"}
+	shamt         = field{0, 1<<6 - 1, "shamt"}
+	dtAddress     = field{-(1 << 8), 1<<8 - 1, "DT_address"}
+	brAddress     = field{-(1 << 25), 1<<25 - 1, "BR_address"}
+	condBrAddress = field{-(1 << 18), 1<<18 - 1, "COND_BR_address"}
+	movImmediate  = field{0, 1<<16 - 1, "MOV_immediate"}
+	movShift      = field{0, 48, "shift"}
+)
+
+// immediateField returns the field the immediate operands of an instruction
+// are encoded in, if it has any.
+func immediateField(mnemonic string) (field, bool) {
+	switch mnemonic {
+	case "CMPI", "LDA":
+		return aluImmediate, true
+	case "LSL", "LSR":
+		return shamt, true
+	}
+	info, ok := isa.Instructions[mnemonic]
+	if !ok {
+		return field{}, false
+	}
+	switch info.Format {
+	case isa.I:
+		return aluImmediate, true
+	case isa.D:
+		return dtAddress, true
+	case isa.IW:
+		return movImmediate, true
+	}
+	return field{}, false
+}
+
+// checkRanges reports the immediates of a malformed instruction that do not
+// fit their field. The operands may be out of place, so each is checked by
+// its kind rather than its position.
+func checkRanges(instruction *ast.Instruction) []*Error {
+	e := &encoder{instruction: instruction}
+	f, ok := immediateField(instruction.Mnemonic)
+
+	for _, o := range instruction.Operands {
+		switch o := o.(type) {
+		case *ast.Immediate:
+			if ok {
+				e.immediate(operand{immediate: o, rng: o.Range}, f)
+			}
+		case *ast.MemoryRef:
+			if ok && o.Offset != nil {
+				e.immediate(operand{immediate: o.Offset, rng: o.Offset.Range}, f)
+			}
+		case *ast.Shift:
+			if o.Amount != nil {
+				e.immediate(operand{immediate: o.Amount, rng: o.Amount.Range}, movShift)
+			}
+		}
+	}
+
+	return e.errs
+}
+
+// encoder accumulates the errors found while encoding one instruction.
+type encoder struct {
+	program     *ast.Program
+	instruction *ast.Instruction
+	errs        []*Error
+
+	// index is the index of the word being encoded, which differs from the
+	// instruction's for the later words of a pseudo-instruction.
+	index int
+}
+
+// encode returns the words an instruction is assembled as, one for each
+// instruction a pseudo-instruction expands to.
+func encode(program *ast.Program, instruction *ast.Instruction) ([]uint32, []*Error) {
+	e := &encoder{program: program, instruction: instruction}
+	instructions := []machineInstruction{{instruction.Mnemonic, flatten(instruction)}}
+	if pseudo, ok := isa.PseudoInstructions[instruction.Mnemonic]; ok {
+		instructions = expand(pseudo, instructions[0].operands, instruction.Range)
+	}
+
+	encodings := []uint32{}
+	for i, machine := range instructions {
+		e.index = instruction.Index + i
+		encodings = append(encodings, e.encode(machine.mnemonic, machine.operands))
+	}
+	return encodings, e.errs
+}
+
+func (e *encoder) encode(mnemonic string, operands []operand) uint32 {
+	instruction := e.instruction
+	info, ok := isa.Instructions[mnemonic]
+	if !ok {
+		e.fail(SyntaxError, instruction.MnemonicRange, fmt.Sprintf("Unknown instruction %s.", mnemonic))
+		return 0
+	}
+	opcode := uint32(info.Opcode)
+
+	var encoding uint32
+	switch info.Format {
+	case isa.R:
+		var rd, rn, rm, sh uint32
+		switch mnemonic {
+		case "LSL", "LSR":
+			rd, rn, sh = e.register(operands[0]), e.register(operands[1]), e.immediate(operands[2], shamt)
+		case "BR", "BLR":
+			rn, rm = e.register(operands[0]), 31
+		case "RET":
+			// RET returns through the link register unless given another
+			rn, rm = isa.LR, 31
+			if len(operands) > 0 {
+				rn = e.register(operands[0])
+			}
+		case "FCMPS", "FCMPD":
+			rn, rm = e.register(operands[0]), e.register(operands[1])
+		case "PRNT":
+			rd = e.register(operands[0])
+		case "PRNL", "DUMP", "HALT":
+		default:
+			rd, rn, rm = e.register(operands[0]), e.register(operands[1]), e.register(operands[2])
+		}
+		if sh == 0 {
+			sh = uint32(info.Shamt)
+		}
+		encoding = opcode<<21 | rm<<16 | sh<<10 | rn<<5 | rd
+	case isa.I:
+		rd, rn := e.register(operands[0]), e.register(operands[1])
+		immediate := e.immediate(operands[2], aluImmediate)
+		encoding = opcode<<22 | immediate<<10 | rn<<5 | rd
+	case isa.D:
+		rt, rn := e.register(operands[0]), e.register(operands[1])
+		address := e.immediate(operands[2], dtAddress) & (1<<9 - 1)
+		encoding = opcode<<21 | address<<12 | rn<<5 | rt
+	case isa.B:
+		offset := e.branch(operands[0], brAddress) & (1<<26 - 1)
+		encoding = opcode<<26 | offset
+	case isa.CB:
+		var rt uint32
+		target := operands[0]
+		if strings.HasPrefix(mnemonic, "B.") {
+			rt = uint32(isa.ConditionCodes[strings.TrimPrefix(mnemonic, "B.")])
+		} else {
+			rt, target = e.register(operands[0]), operands[1]
+		}
+		offset := e.branch(target, condBrAddress) & (1<<19 - 1)
+		encoding = opcode<<24 | offset<<5 | rt
+	case isa.IW:
+		rd := e.register(operands[0])
+		immediate := e.immediate(operands[1], movImmediate)
+		shift := e.immediate(operands[2], movShift)
+		if shift%16 != 0 {
+			e.fail(RangeError, operands[2].rng, fmt.Sprintf("Shift %s is not valid for %s. Expected #0, #16, #32 or #48.", operands[2].immediate.Text, instruction.Mnemonic))
+		}
+		encoding = opcode<<23 | (shift/16)<<21 | immediate<<5 | rd
+	}
+
+	return encoding
+}
+
+func (e *encoder) fail(kind ErrorKind, r ast.Range, message string) {
+	e.errs = append(e.errs, &Error{kind, r, message})
+}
+
+// register returns the number of a register operand.
+func (e *encoder) register(o operand) uint32 {
+	if number, ok := isa.IntegerRegister(o.register); ok {
+		return uint32(number)
+	}
+	if number, ok := isa.FloatRegister(o.register); ok {
+		return uint32(number)
+	}
+	e.fail(SyntaxError, o.rng, fmt.Sprintf("Unknown register %s.", o.register))
+	return 0
+}
+
+// immediate returns the value of an immediate operand as two's complement,
+// reporting values that do not fit in f. A label operand, which ADR places
+// where an immediate belongs, has the label's address as its value.
+func (e *encoder) immediate(o operand, f field) uint32 {
+	if o.immediate == nil {
+		return e.address(o, f)
+	}
+	value := o.immediate.Value
+	if value < f.min || value > f.max {
+		e.fail(RangeError, o.rng, fmt.Sprintf("Immediate %s is out of range for %s. %s must be between %d and %d.", o.immediate.Text, e.instruction.Mnemonic, f.name, f.min, f.max))
+		return 0
+	}
+	return uint32(value)
+}
+
+// branch returns the offset in instructions from the instruction being
+// encoded to the label of a branch operand.
+func (e *encoder) branch(o operand, f field) uint32 {
+	target, ok := e.program.Label(o.label)
+	if !ok {
+		e.fail(LabelError, o.rng, fmt.Sprintf("Label '%s' is not defined.", o.label))
+		return 0
+	}
+	offset := int64(target.Index - e.index)
+	if offset < f.min || offset > f.max {
+		e.fail(RangeError, o.rng, fmt.Sprintf("Branch to '%s' is out of range for %s. Offset of %d instructions does not fit in %s.", o.label, e.instruction.Mnemonic, offset, f.name))
+		return 0
+	}
+	return uint32(offset)
+}
+
+// address returns the address of the label of an operand, reporting labels
+// that are not defined and addresses that do not fit in f.
+func (e *encoder) address(o operand, f field) uint32 {
+	target, ok := e.program.Label(o.label)
+	if !ok {
+		e.fail(LabelError, o.rng, fmt.Sprintf("Label '%s' is not defined.", o.label))
+		return 0
+	}
+	address := int64(target.Index) * 4
+	if address < f.min || address > f.max {
+		e.fail(RangeError, o.rng, fmt.Sprintf("Address of '%s' is out of range for %s. Address %d does not fit in %s.", o.label, e.instruction.Mnemonic, address, f.name))
+		return 0
+	}
+	return uint32(address)
+}
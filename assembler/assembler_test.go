@@ -0,0 +1,141 @@
+package assembler_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"server/assembler"
+	"server/parser"
+)
+
+func TestAssemble(t *testing.T) {
+	inputs := []struct {
+		source   string
+		expected uint32
+	}{
+		{"ADD X9, X20, X21", 0x8B150289},
+		{"LDUR X9, [X22, #64]", 0xF84402C9},
+		{"LDUR X1, [SP, #-8]", 0xF85F8381},
+		{"ADDI X9, X9, #1", 0x91000529},
+		{"MOVZ X9, #255, LSL #16", 0xD2A01FE9},
+		{"BR X30", 0xD61F03C0},
+		{"BLR X1", 0xD63F0020},
+		{"RET", 0xD65F03C0},
+		{"RET X5", 0xD65F00A0},
+		{"MOV X1, X2", 0xAA0203E1},
+		{"CMP X1, X2", 0xEB02003F},
+		{"CMPI X1, #4", 0xF100103F},
+		{"HALT\ndata:\nADR X1, data", 0x910013E1},
+		{"MNEG X1, X2, X3", 0xCB0103E1},
+		{"MNEG X1, X2, X3\nB done\ndone:", 0x14000001},
+		{"SMULL X1, X2, X3", 0x9B037C41},
+		{"UMULL X1, X2, X3", 0x9B037C41},
+		{"LSL X1, X2, #3", 0xD3600C41},
+		{"MUL X1, X2, X3", 0x9B037C41},
+		{"FADDS S1, S2, S3", 0x1E232841},
+		{"LDURD D1, [X2, #8]", 0xFC408041},
+		{"HALT", 0xFFE00000},
+		{"loop:\nB loop", 0x14000000},
+		{"CBZ X1, done\ndone:", 0xB4000021},
+		{"top:\nHALT\nB.NE top", 0x54FFFFE1},
+	}
+
+	for _, in := range inputs {
+		assembly, errs := assembler.Assemble(parser.ParseProgram(in.source))
+		if len(errs) > 0 {
+			t.Errorf("Unexpected errors %v. Input: %q", errs, in.source)
+			continue
+		}
+		word := assembly.Words[len(assembly.Words)-1]
+		if word.Encoding != in.expected {
+			t.Errorf("Expected encoding %08X, got %08X. Input: %q", in.expected, word.Encoding, in.source)
+		}
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	inputs := []struct {
+		source  string
+		kind    assembler.ErrorKind
+		message string
+	}{
+		{"ADDI X0, X0, #5000", assembler.RangeError, "Immediate #5000 is out of range for ADDI. ALU_immediate must be between 0 and 4095."},
+		{"CMPI X0, #5000", assembler.RangeError, "Immediate #5000 is out of range for CMPI. ALU_immediate must be between 0 and 4095."},
+		{"MOVK X0, #1, LSL #8", assembler.RangeError, "Shift #8 is not valid for MOVK. Expected #0, #16, #32 or #48."},
+		{"B nowhere", assembler.LabelError, "Label 'nowhere' is not defined."},
+		{"ADR X1, nowhere", assembler.LabelError, "Label 'nowhere' is not defined."},
+		{"ADD X45, X1, X2", assembler.SyntaxError, "Unknown register X45."},
+		{"ADD X1 X2", assembler.SyntaxError, "Cannot assemble malformed ADD instruction."},
+	}
+
+	for _, in := range inputs {
+		_, errs := assembler.Assemble(parser.ParseProgram(in.source))
+		if len(errs) != 1 {
+			t.Errorf("Expected 1 error, got %v. Input: %q", errs, in.source)
+			continue
+		}
+		if errs[0].Kind != in.kind || errs[0].Message != in.message {
+			t.Errorf("Expected error %q, got %q. Input: %q", in.message, errs[0].Message, in.source)
+		}
+	}
+}
+
+// TestBranchRange checks branch offsets, and the label addresses of ADR, at
+// the limits of their fields. The label is moved to a synthetic instruction
+// index rather than padding the source with instructions.
+func TestBranchRange(t *testing.T) {
+	inputs := []struct {
+		source string
+		target int
+		ok     bool
+	}{
+		{"CBZ X0, far\nfar:", 1<<18 - 1, true},
+		{"CBZ X0, far\nfar:", 1 << 18, false},
+		{"CBZ X0, far\nfar:", -(1 << 18), true},
+		{"CBZ X0, far\nfar:", -(1 << 18) - 1, false},
+		{"B.NE far\nfar:", 1 << 18, false},
+		{"B far\nfar:", 1 << 18, true},
+		{"B far\nfar:", 1<<25 - 1, true},
+		{"B far\nfar:", 1 << 25, false},
+		{"BL far\nfar:", -(1 << 25) - 1, false},
+		{"ADR X1, far\nfar:", 1023, true},
+		{"ADR X1, far\nfar:", 1024, false},
+	}
+
+	for _, in := range inputs {
+		program := parser.ParseProgram(in.source)
+		program.Labels()[0].Index = in.target
+		_, errs := assembler.Assemble(program)
+		if in.ok && len(errs) > 0 {
+			t.Errorf("Unexpected errors %v. Input: %q to %d", errs, in.source, in.target)
+		}
+		if !in.ok && (len(errs) != 1 || errs[0].Kind != assembler.RangeError) {
+			t.Errorf("Expected a range error, got %v. Input: %q to %d", errs, in.source, in.target)
+		}
+	}
+}
+
+func TestOutput(t *testing.T) {
+	source := "// add one\nmain:\nADDI X9, X9, #1\nHALT"
+	assembly, errs := assembler.Assemble(parser.ParseProgram(source))
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors %v.", errs)
+	}
+
+	if !bytes.Equal(assembly.Binary(), []byte{0x29, 0x05, 0x00, 0x91, 0x00, 0x00, 0xE0, 0xFF}) {
+		t.Errorf("Incorrect binary % X.", assembly.Binary())
+	}
+	if assembly.Hex() != "91000529\nFFE00000\n" {
+		t.Errorf("Incorrect hex %q.", assembly.Hex())
+	}
+
+	expected := "" +
+		"                  // add one\n" +
+		"                  main:\n" +
+		"00000000  91000529  ADDI X9, X9, #1\n" +
+		"00000004  FFE00000  HALT\n"
+	if listing := assembly.Listing(strings.Split(source, "\n")); listing != expected {
+		t.Errorf("Incorrect listing %q.", listing)
+	}
+}
@@ -0,0 +1,45 @@
+package assembler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Binary returns the machine code as raw little-endian 32-bit words, the byte
+// order the simulator's memory and ARMv8 use.
+func (a *Assembly) Binary() []byte {
+	out := make([]byte, 4*len(a.Words))
+	for i, word := range a.Words {
+		binary.LittleEndian.PutUint32(out[4*i:], word.Encoding)
+	}
+	return out
+}
+
+// Hex returns the machine code as one hexadecimal word per line.
+func (a *Assembly) Hex() string {
+	var b strings.Builder
+	for _, word := range a.Words {
+		fmt.Fprintf(&b, "%08X\n", word.Encoding)
+	}
+	return b.String()
+}
+
+// Listing pairs each line of source with the address and encoding of the
+// instruction on it, if any.
+func (a *Assembly) Listing(source []string) string {
+	words := map[int]Word{}
+	for _, word := range a.Words {
+		words[word.Line] = word
+	}
+
+	var b strings.Builder
+	for i, line := range source {
+		if word, ok := words[i]; ok {
+			fmt.Fprintf(&b, "%08X  %08X  %s\n", word.Address, word.Encoding, line)
+		} else {
+			fmt.Fprintf(&b, "%s\n", strings.TrimRight(fmt.Sprintf("%18s%s", "", line), " "))
+		}
+	}
+	return b.String()
+}
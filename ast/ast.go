@@ -0,0 +1,197 @@
+// Package ast defines a typed syntax tree for LEGv8 programs. It has no
+// dependency on the language server protocol so that other tools, such as
+// graders and assemblers, can consume parsed programs directly.
+package ast
+
+import "server/isa"
+
+// Position is a zero-based line and byte column in a source file.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Range is a span of source text. End is exclusive.
+type Range struct {
+	Start Position
+	End   Position
+}
+
+// Program is a parsed LEGv8 source file.
+type Program struct {
+	Lines []*Line
+}
+
+// Line is a single line of source. A line holds a label definition, an
+// instruction, or neither.
+type Line struct {
+	Number      int
+	Label       *LabelDef
+	Instruction *Instruction
+}
+
+// LabelDef is the definition of a label, such as "loop:".
+type LabelDef struct {
+	Name  string
+	Range Range
+
+	// Index is the index of the instruction the label refers to, which is the
+	// first instruction after its definition.
+	Index int
+}
+
+// Instruction is an instruction and its operands.
+type Instruction struct {
+	Mnemonic      string
+	MnemonicRange Range
+
+	// Format is the encoding format of the instruction. Pseudo-instructions
+	// have the format of the instruction they are assembled as, and unknown
+	// instructions have none.
+	Format   isa.Format
+	Operands []Operand
+	Range    Range
+
+	// Index is the index of the instruction within the program. The
+	// instruction's address is four times its index. Pseudo-instructions
+	// assembled as several instructions take an index for each.
+	Index int
+
+	// Valid is set when the operands have the shape the instruction expects.
+	// Operands of invalid instructions are collected on a best-effort basis.
+	Valid bool
+}
+
+// Operand is an operand of an instruction.
+type Operand interface {
+	OperandRange() Range
+	operand()
+}
+
+// Register is a register operand, such as X1, SP, XZR, S2 or D3.
+type Register struct {
+	Name  string
+	Range Range
+}
+
+// Immediate is an immediate operand, such as #12.
+type Immediate struct {
+	Value int64
+	Text  string
+	Range Range
+}
+
+// MemoryRef is a memory operand, such as [X1, #8].
+type MemoryRef struct {
+	Base   *Register
+	Offset *Immediate
+	Range  Range
+}
+
+// LabelRef is a reference to a label in a branch operand.
+type LabelRef struct {
+	Name  string
+	Range Range
+}
+
+// Shift is a shift operand of a move wide instruction, such as LSL #16.
+type Shift struct {
+	Amount *Immediate
+	Range  Range
+}
+
+func (r *Register) OperandRange() Range  { return r.Range }
+func (i *Immediate) OperandRange() Range { return i.Range }
+func (m *MemoryRef) OperandRange() Range { return m.Range }
+func (l *LabelRef) OperandRange() Range  { return l.Range }
+func (s *Shift) OperandRange() Range     { return s.Range }
+
+func (*Register) operand()  {}
+func (*Immediate) operand() {}
+func (*MemoryRef) operand() {}
+func (*LabelRef) operand()  {}
+func (*Shift) operand()     {}
+
+// Instructions returns every instruction in the program, in order.
+func (p *Program) Instructions() []*Instruction {
+	instructions := []*Instruction{}
+	for _, line := range p.Lines {
+		if line.Instruction != nil {
+			instructions = append(instructions, line.Instruction)
+		}
+	}
+	return instructions
+}
+
+// Labels returns the label definitions in the program, in order. Labels
+// defined more than once appear once for each definition.
+func (p *Program) Labels() []*LabelDef {
+	labels := []*LabelDef{}
+	for _, line := range p.Lines {
+		if line.Label != nil {
+			labels = append(labels, line.Label)
+		}
+	}
+	return labels
+}
+
+// Label returns the first definition of the label name.
+func (p *Program) Label(name string) (*LabelDef, bool) {
+	for _, line := range p.Lines {
+		if line.Label != nil && line.Label.Name == name {
+			return line.Label, true
+		}
+	}
+	return nil, false
+}
+
+// LabelRefs returns every operand that refers to the label name.
+func (p *Program) LabelRefs(name string) []*LabelRef {
+	refs := []*LabelRef{}
+	for _, instruction := range p.Instructions() {
+		for _, operand := range instruction.Operands {
+			if ref, ok := operand.(*LabelRef); ok && ref.Name == name {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// Immediates returns the immediate operands of the instruction in order,
+// including those within memory references and shifts.
+func (i *Instruction) Immediates() []*Immediate {
+	immediates := []*Immediate{}
+	for _, operand := range i.Operands {
+		switch o := operand.(type) {
+		case *Immediate:
+			immediates = append(immediates, o)
+		case *MemoryRef:
+			if o.Offset != nil {
+				immediates = append(immediates, o.Offset)
+			}
+		case *Shift:
+			if o.Amount != nil {
+				immediates = append(immediates, o.Amount)
+			}
+		}
+	}
+	return immediates
+}
+
+// Registers returns the register operands of the instruction in order,
+// including the base registers of memory references.
+func (i *Instruction) Registers() []*Register {
+	registers := []*Register{}
+	for _, operand := range i.Operands {
+		switch o := operand.(type) {
+		case *Register:
+			registers = append(registers, o)
+		case *MemoryRef:
+			if o.Base != nil {
+				registers = append(registers, o.Base)
+			}
+		}
+	}
+	return registers
+}
@@ -0,0 +1,45 @@
+package ast
+
+import (
+	"testing"
+)
+
+func TestProgram(t *testing.T) {
+	loop := &LabelDef{Name: "loop"}
+	ref := &LabelRef{Name: "loop"}
+	offset := &Immediate{Value: 8, Text: "#8"}
+	base := &Register{Name: "SP"}
+
+	program := &Program{
+		Lines: []*Line{
+			{Number: 0, Label: loop},
+			{Number: 1, Instruction: &Instruction{
+				Mnemonic: "LDUR",
+				Operands: []Operand{&Register{Name: "X1"}, &MemoryRef{Base: base, Offset: offset}},
+			}},
+			{Number: 2},
+			{Number: 3, Instruction: &Instruction{Mnemonic: "B", Operands: []Operand{ref}}},
+		},
+	}
+
+	if len(program.Instructions()) != 2 {
+		t.Errorf("Expected 2 instructions, got %d.", len(program.Instructions()))
+	}
+	if label, ok := program.Label("loop"); !ok || label != loop {
+		t.Errorf("Expected to find label loop.")
+	}
+	if _, ok := program.Label("missing"); ok {
+		t.Errorf("Found label that is not defined.")
+	}
+	if refs := program.LabelRefs("loop"); len(refs) != 1 || refs[0] != ref {
+		t.Errorf("Incorrect references %v.", refs)
+	}
+
+	ldur := program.Lines[1].Instruction
+	if immediates := ldur.Immediates(); len(immediates) != 1 || immediates[0] != offset {
+		t.Errorf("Incorrect immediates %v.", immediates)
+	}
+	if registers := ldur.Registers(); len(registers) != 2 || registers[1] != base {
+		t.Errorf("Incorrect registers %v.", registers)
+	}
+}
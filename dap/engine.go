@@ -0,0 +1,167 @@
+package dap
+
+// mode is how far resuming the program runs before stopping again.
+type mode int
+
+const (
+	continueMode mode = iota
+	// nextMode steps over BL and BLR calls.
+	nextMode
+	stepInMode
+	// stepOutMode runs until the current call returns through BR X30 or RET.
+	stepOutMode
+)
+
+// frame is a call made with BL or BLR that has not returned yet.
+type frame struct {
+	// call is the address of the BL or BLR instruction.
+	call uint64
+	// entry is the address branched to.
+	entry uint64
+}
+
+func (s *Session) handleResume(m mode) func(*request) error {
+	return func(r *request) error {
+		body := map[string]interface{}{"allThreadsContinued": true}
+		if err := s.out.respond(r, body); err != nil {
+			return err
+		}
+		s.resume(m, true)
+		return nil
+	}
+}
+
+// resume runs the program on its own goroutine so the session can still
+// answer requests, such as pause, while it runs. A breakpoint on the current
+// line is ignored when resuming from where the program stopped.
+func (s *Session) resume(m mode, fromStop bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.machine == nil || s.running {
+		return
+	}
+	if s.failed || s.machine.Halted {
+		go s.terminate()
+		return
+	}
+	s.running = true
+	s.pause = false
+	go s.run(m, len(s.frames), fromStop)
+}
+
+// run executes the program until it stops, where depth is the number of
+// calls in progress when it was resumed.
+func (s *Session) run(m mode, depth int, fromStop bool) {
+	for first := fromStop; ; first = false {
+		s.mu.Lock()
+		machine := s.machine
+
+		reason := ""
+		switch {
+		case machine.Halted:
+			s.running = false
+			s.mu.Unlock()
+			s.terminate()
+			return
+		case s.withinLine():
+			// finish the instructions of a pseudo-instruction before stopping
+		case s.pause:
+			reason = "pause"
+		case !first && s.breakpoints[machine.Line()]:
+			reason = "breakpoint"
+		}
+		if reason != "" {
+			s.running = false
+			s.mu.Unlock()
+			s.stopped(reason, "")
+			return
+		}
+
+		if err := s.step(); err != nil {
+			s.running = false
+			s.failed = true
+			s.mu.Unlock()
+			s.out.event("output", map[string]interface{}{"category": "stderr", "output": err.Error() + "\n"})
+			s.stopped("exception", err.Error())
+			return
+		}
+
+		done := false
+		switch m {
+		case stepInMode:
+			done = true
+		case nextMode:
+			done = len(s.frames) <= depth
+		case stepOutMode:
+			done = len(s.frames) < depth
+		}
+		if done && !machine.Halted && !s.withinLine() {
+			s.running = false
+			s.mu.Unlock()
+			s.stopped("step", "")
+			return
+		}
+		s.mu.Unlock()
+	}
+}
+
+// withinLine reports whether the next instruction continues the line of the
+// one before it, as the later instructions of a pseudo-instruction do. It
+// must be called with s.mu held.
+func (s *Session) withinLine() bool {
+	m := s.machine
+	return m.PC >= 4 && m.LineAt(m.PC-4) == m.Line()
+}
+
+// step executes one instruction, tracking calls made with BL or BLR and
+// returns made with BR or RET. It must be called with s.mu held.
+func (s *Session) step() error {
+	machine := s.machine
+	info, _ := machine.Next()
+	address := machine.PC
+	if err := machine.Step(); err != nil {
+		return err
+	}
+	if info == nil {
+		return nil
+	}
+
+	switch info.Mnemonic {
+	case "BL", "BLR":
+		s.frames = append(s.frames, frame{call: address, entry: machine.PC})
+	case "BR", "RET":
+		if n := len(s.frames); n > 0 && machine.PC == s.frames[n-1].call+4 {
+			s.frames = s.frames[:n-1]
+		}
+	}
+	return nil
+}
+
+func (s *Session) stopped(reason, text string) error {
+	body := map[string]interface{}{
+		"reason":            reason,
+		"threadId":          threadID,
+		"allThreadsStopped": true,
+	}
+	if text != "" {
+		body["text"] = text
+		body["description"] = text
+	}
+	return s.out.event("stopped", body)
+}
+
+// terminate ends the session. The program goroutine and the terminate
+// request may both end it, so the events are only sent the first time.
+func (s *Session) terminate() error {
+	s.mu.Lock()
+	ended := s.ended
+	s.ended = true
+	s.mu.Unlock()
+	if ended {
+		return nil
+	}
+	if err := s.out.event("exited", map[string]interface{}{"exitCode": 0}); err != nil {
+		return err
+	}
+	return s.out.event("terminated", nil)
+}
@@ -0,0 +1,96 @@
+// Package dap implements the Debug Adapter Protocol for LEGv8 programs,
+// running them in the simulator so editors can set breakpoints, step through
+// instructions and inspect registers and memory.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// message is the envelope shared by requests, responses and events.
+type message struct {
+	Seq  int    `json:"seq"`
+	Type string `json:"type"`
+}
+
+type request struct {
+	message
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type response struct {
+	message
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type event struct {
+	message
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+// readMessage reads a single message framed with a Content-Length header.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %v", err)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// writer frames outgoing messages and numbers them. It is safe for use by
+// the request loop and the running program at the same time.
+type writer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	seq int
+}
+
+// send numbers and writes a message whose envelope is m.
+func (w *writer) send(m *message, v interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.seq++
+	m.Seq = w.seq
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.w, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	return err
+}
+
+func (w *writer) respond(r *request, body interface{}) error {
+	res := &response{message: message{Type: "response"}, RequestSeq: r.Seq, Success: true, Command: r.Command, Body: body}
+	return w.send(&res.message, res)
+}
+
+func (w *writer) fail(r *request, msg string) error {
+	res := &response{message: message{Type: "response"}, RequestSeq: r.Seq, Success: false, Command: r.Command, Message: msg}
+	return w.send(&res.message, res)
+}
+
+func (w *writer) event(name string, body interface{}) error {
+	e := &event{message: message{Type: "event"}, Event: name, Body: body}
+	return w.send(&e.message, e)
+}
@@ -0,0 +1,282 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"server/ast"
+	"server/parser"
+	"server/simulator"
+)
+
+// threadID is the only thread a LEGv8 program has.
+const threadID = 1
+
+// Session is a debugging session for a single program.
+type Session struct {
+	out      *writer
+	handlers map[string]func(*request) error
+
+	// linesStartAt1 is false when the client numbers lines from zero.
+	linesStartAt1 bool
+	stopOnEntry   bool
+
+	path    string
+	program *ast.Program
+
+	// mu guards the machine, call stack and breakpoints, which the program
+	// goroutine updates while it runs.
+	mu          sync.Mutex
+	machine     *simulator.Machine
+	frames      []frame
+	breakpoints map[int]bool
+	running     bool
+	pause       bool
+	failed      bool
+
+	// ended is set once the exited and terminated events have been sent.
+	ended bool
+}
+
+// Serve runs a debugging session over a stream until the client disconnects.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &Session{
+		out:           &writer{w: w},
+		linesStartAt1: true,
+		breakpoints:   map[int]bool{},
+	}
+	s.buildHandlers()
+
+	in := bufio.NewReader(r)
+	for {
+		content, err := readMessage(in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(content, &req); err != nil {
+			return err
+		}
+		handler, ok := s.handlers[req.Command]
+		if !ok {
+			if err := s.out.fail(&req, fmt.Sprintf("Unsupported command '%s'.", req.Command)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := handler(&req); err != nil {
+			return err
+		}
+		if req.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+func (s *Session) buildHandlers() {
+	s.handlers = map[string]func(*request) error{
+		"initialize":              s.handleInitialize,
+		"launch":                  s.handleLaunch,
+		"setBreakpoints":          s.handleSetBreakpoints,
+		"setExceptionBreakpoints": s.handleEmpty,
+		"configurationDone":       s.handleConfigurationDone,
+		"threads":                 s.handleThreads,
+		"stackTrace":              s.handleStackTrace,
+		"scopes":                  s.handleScopes,
+		"variables":               s.handleVariables,
+		"evaluate":                s.handleEvaluate,
+		"readMemory":              s.handleReadMemory,
+		"continue":                s.handleResume(continueMode),
+		"next":                    s.handleResume(nextMode),
+		"stepIn":                  s.handleResume(stepInMode),
+		"stepOut":                 s.handleResume(stepOutMode),
+		"pause":                   s.handlePause,
+		"terminate":               s.handleTerminate,
+		"disconnect":              s.handleTerminate,
+	}
+}
+
+// clientLine converts a zero-based line to the client's numbering.
+func (s *Session) clientLine(line int) int {
+	if s.linesStartAt1 {
+		return line + 1
+	}
+	return line
+}
+
+func (s *Session) handleEmpty(r *request) error {
+	return s.out.respond(r, nil)
+}
+
+func (s *Session) handleInitialize(r *request) error {
+	var args struct {
+		LinesStartAt1 *bool `json:"linesStartAt1"`
+	}
+	if err := json.Unmarshal(r.Arguments, &args); err != nil {
+		return s.out.fail(r, "Invalid initialize arguments.")
+	}
+	if args.LinesStartAt1 != nil {
+		s.linesStartAt1 = *args.LinesStartAt1
+	}
+
+	capabilities := map[string]interface{}{
+		"supportsConfigurationDoneRequest": true,
+		"supportsReadMemoryRequest":        true,
+		"supportsTerminateRequest":         true,
+		"supportsEvaluateForHovers":        true,
+	}
+	if err := s.out.respond(r, capabilities); err != nil {
+		return err
+	}
+	return s.out.event("initialized", nil)
+}
+
+func (s *Session) handleLaunch(r *request) error {
+	var args struct {
+		Program     string `json:"program"`
+		StopOnEntry bool   `json:"stopOnEntry"`
+	}
+	if err := json.Unmarshal(r.Arguments, &args); err != nil || args.Program == "" {
+		return s.out.fail(r, "Launch requires the path of a program.")
+	}
+
+	text, err := ioutil.ReadFile(args.Program)
+	if err != nil {
+		return s.out.fail(r, fmt.Sprintf("Cannot read %s: %v", args.Program, err))
+	}
+	program := parser.ParseProgram(string(text))
+	machine, errs := simulator.New(program, &output{s.out})
+	if len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, err := range errs {
+			messages[i] = fmt.Sprintf("%s:%s", filepath.Base(args.Program), err)
+		}
+		return s.out.fail(r, "Cannot assemble program.\n"+strings.Join(messages, "\n"))
+	}
+
+	s.mu.Lock()
+	s.path = args.Program
+	s.program = program
+	s.machine = machine
+	s.stopOnEntry = args.StopOnEntry
+	s.mu.Unlock()
+	return s.out.respond(r, nil)
+}
+
+func (s *Session) handleSetBreakpoints(r *request) error {
+	var args struct {
+		Breakpoints []struct {
+			Line int `json:"line"`
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(r.Arguments, &args); err != nil {
+		return s.out.fail(r, "Invalid setBreakpoints arguments.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type breakpoint struct {
+		Verified bool   `json:"verified"`
+		Line     int    `json:"line,omitempty"`
+		Message  string `json:"message,omitempty"`
+	}
+	s.breakpoints = map[int]bool{}
+	breakpoints := []breakpoint{}
+	for _, requested := range args.Breakpoints {
+		line := requested.Line
+		if s.linesStartAt1 {
+			line--
+		}
+		// a breakpoint on a blank line or label stops at the next instruction
+		if instructionLine, ok := s.instructionLineFrom(line); ok {
+			s.breakpoints[instructionLine] = true
+			breakpoints = append(breakpoints, breakpoint{Verified: true, Line: s.clientLine(instructionLine)})
+		} else {
+			breakpoints = append(breakpoints, breakpoint{Verified: false, Line: requested.Line, Message: "No instruction on or after this line."})
+		}
+	}
+
+	return s.out.respond(r, map[string]interface{}{"breakpoints": breakpoints})
+}
+
+// instructionLineFrom returns the first line at or after line with an
+// instruction on it.
+func (s *Session) instructionLineFrom(line int) (int, bool) {
+	if s.program == nil {
+		return line, true
+	}
+	for _, instruction := range s.program.Instructions() {
+		if instruction.Range.Start.Line >= line {
+			return instruction.Range.Start.Line, true
+		}
+	}
+	return 0, false
+}
+
+func (s *Session) handleConfigurationDone(r *request) error {
+	if err := s.out.respond(r, nil); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	launched, stopOnEntry := s.machine != nil, s.stopOnEntry
+	s.mu.Unlock()
+	if !launched {
+		return nil
+	}
+	if stopOnEntry {
+		return s.stopped("entry", "")
+	}
+	s.resume(continueMode, false)
+	return nil
+}
+
+func (s *Session) handleThreads(r *request) error {
+	threads := []map[string]interface{}{{"id": threadID, "name": "main"}}
+	return s.out.respond(r, map[string]interface{}{"threads": threads})
+}
+
+func (s *Session) handlePause(r *request) error {
+	s.mu.Lock()
+	s.pause = true
+	s.mu.Unlock()
+	return s.out.respond(r, nil)
+}
+
+func (s *Session) handleTerminate(r *request) error {
+	s.mu.Lock()
+	if s.machine != nil {
+		s.machine.Halted = true
+	}
+	s.mu.Unlock()
+	if err := s.out.respond(r, nil); err != nil {
+		return err
+	}
+	if r.Command == "terminate" {
+		return s.terminate()
+	}
+	return nil
+}
+
+// output sends text written by PRNT, PRNL and DUMP to the debug console.
+type output struct {
+	out *writer
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	body := map[string]interface{}{"category": "stdout", "output": string(p)}
+	if err := o.out.event("output", body); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,341 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const program = `main:
+    ADDI X0, XZR, #2
+    BL double
+    PRNT X0
+    HALT
+double:
+    ADD X0, X0, X0
+    BR LR`
+
+// client drives a session the way an editor would.
+type client struct {
+	t        *testing.T
+	w        io.Writer
+	messages chan map[string]interface{}
+	seq      int
+	output   strings.Builder
+	path     string
+}
+
+func newClient(t *testing.T, source string) *client {
+	dir, err := ioutil.TempDir("", "dap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "program.legv8")
+	if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	c := &client{t: t, w: inWriter, messages: make(chan map[string]interface{}, 100), path: path}
+
+	go Serve(inReader, outWriter)
+	go func() {
+		r := bufio.NewReader(outReader)
+		for {
+			content, err := readMessage(r)
+			if err != nil {
+				close(c.messages)
+				return
+			}
+			var m map[string]interface{}
+			json.Unmarshal(content, &m)
+			c.messages <- m
+		}
+	}()
+	t.Cleanup(func() {
+		inWriter.Close()
+		os.RemoveAll(dir)
+	})
+	return c
+}
+
+func (c *client) send(command string, args interface{}) {
+	c.seq++
+	content, _ := json.Marshal(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	c.w.Write(append([]byte("Content-Length: "+strconv.Itoa(len(content))+"\r\n\r\n"), content...))
+}
+
+// wait reads messages until one of the given type and name arrives,
+// collecting program output on the way.
+func (c *client) wait(kind, name string) map[string]interface{} {
+	c.t.Helper()
+	for {
+		select {
+		case m, ok := <-c.messages:
+			if !ok {
+				c.t.Fatalf("Session ended while waiting for %s %s.", kind, name)
+			}
+			if m["type"] == "event" && m["event"] == "output" {
+				c.output.WriteString(m["body"].(map[string]interface{})["output"].(string))
+			}
+			if m["type"] == kind && (m["command"] == name || m["event"] == name) {
+				return m
+			}
+		case <-time.After(5 * time.Second):
+			c.t.Fatalf("Timed out waiting for %s %s.", kind, name)
+		}
+	}
+}
+
+func (c *client) request(command string, args interface{}) map[string]interface{} {
+	c.t.Helper()
+	c.send(command, args)
+	res := c.wait("response", command)
+	if res["success"] != true {
+		c.t.Fatalf("Request %s failed: %v", command, res["message"])
+	}
+	body, _ := res["body"].(map[string]interface{})
+	return body
+}
+
+// start launches the program and sets breakpoints on the given lines.
+func (c *client) start(stopOnEntry bool, lines ...int) map[string]interface{} {
+	c.t.Helper()
+	c.request("initialize", map[string]interface{}{"adapterID": "legv8"})
+	c.wait("event", "initialized")
+	c.request("launch", map[string]interface{}{"program": c.path, "stopOnEntry": stopOnEntry})
+	breakpoints := []map[string]int{}
+	for _, line := range lines {
+		breakpoints = append(breakpoints, map[string]int{"line": line})
+	}
+	body := c.request("setBreakpoints", map[string]interface{}{"source": map[string]string{"path": c.path}, "breakpoints": breakpoints})
+	c.request("configurationDone", nil)
+	return body
+}
+
+// stopped waits for the program to stop and returns the reason and the line
+// of each stack frame.
+func (c *client) stopped() (string, []int) {
+	c.t.Helper()
+	reason := c.wait("event", "stopped")["body"].(map[string]interface{})["reason"].(string)
+	lines := []int{}
+	for _, f := range c.request("stackTrace", map[string]int{"threadId": threadID})["stackFrames"].([]interface{}) {
+		lines = append(lines, int(f.(map[string]interface{})["line"].(float64)))
+	}
+	return reason, lines
+}
+
+func (c *client) evaluate(expression string) string {
+	c.t.Helper()
+	return c.request("evaluate", map[string]string{"expression": expression})["result"].(string)
+}
+
+func equalLines(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBreakpointsAndStepOut(t *testing.T) {
+	c := newClient(t, program)
+	c.start(false, 7)
+
+	reason, lines := c.stopped()
+	if reason != "breakpoint" || !equalLines(lines, []int{7, 3}) {
+		t.Errorf("Expected breakpoint at [7 3], got %s at %v.", reason, lines)
+	}
+	if x0 := c.evaluate("X0"); x0 != "2 (0x2)" {
+		t.Errorf("Expected X0 to be 2, got %s.", x0)
+	}
+
+	c.request("stepOut", map[string]int{"threadId": threadID})
+	reason, lines = c.stopped()
+	if reason != "step" || !equalLines(lines, []int{4}) {
+		t.Errorf("Expected step to [4], got %s at %v.", reason, lines)
+	}
+
+	c.request("continue", map[string]int{"threadId": threadID})
+	c.wait("event", "terminated")
+	if !strings.HasPrefix(c.output.String(), "X0:  0x0000000000000004 (4)\n") {
+		t.Errorf("Incorrect output %q.", c.output.String())
+	}
+}
+
+func TestStepping(t *testing.T) {
+	c := newClient(t, program)
+	c.start(true)
+
+	steps := []struct {
+		command string
+		lines   []int
+	}{
+		{"next", []int{3}},
+		{"next", []int{4}},
+	}
+	if reason, lines := c.stopped(); reason != "entry" || !equalLines(lines, []int{2}) {
+		t.Errorf("Expected entry at [2], got %s at %v.", reason, lines)
+	}
+	for _, step := range steps {
+		c.request(step.command, map[string]int{"threadId": threadID})
+		if _, lines := c.stopped(); !equalLines(lines, step.lines) {
+			t.Errorf("Expected %s to stop at %v, got %v.", step.command, step.lines, lines)
+		}
+	}
+
+	c = newClient(t, program)
+	c.start(true)
+	c.stopped()
+	steps = []struct {
+		command string
+		lines   []int
+	}{
+		{"stepIn", []int{3}},
+		{"stepIn", []int{7, 3}},
+		{"stepIn", []int{8, 3}},
+		{"stepIn", []int{4}},
+	}
+	for _, step := range steps {
+		c.request(step.command, map[string]int{"threadId": threadID})
+		if _, lines := c.stopped(); !equalLines(lines, step.lines) {
+			t.Errorf("Expected %s to stop at %v, got %v.", step.command, step.lines, lines)
+		}
+	}
+}
+
+// TestPseudoInstructionStep checks that a pseudo-instruction assembled as
+// several instructions is stepped over and stopped at as a single line.
+func TestPseudoInstructionStep(t *testing.T) {
+	c := newClient(t, "ADDI X1, XZR, #2\nMNEG X1, X1, X1\nHALT")
+	c.start(true, 2)
+	c.stopped()
+
+	c.request("continue", map[string]int{"threadId": threadID})
+	if reason, lines := c.stopped(); reason != "breakpoint" || !equalLines(lines, []int{2}) {
+		t.Errorf("Expected breakpoint at [2], got %s at %v.", reason, lines)
+	}
+	c.request("stepIn", map[string]int{"threadId": threadID})
+	if reason, lines := c.stopped(); reason != "step" || !equalLines(lines, []int{3}) {
+		t.Errorf("Expected step to [3], got %s at %v.", reason, lines)
+	}
+	if x1 := c.evaluate("X1"); x1 != "-4 (0xFFFFFFFFFFFFFFFC)" {
+		t.Errorf("Expected X1 to be -4, got %s.", x1)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	c := newClient(t, program)
+	c.start(false, 4)
+	c.stopped()
+
+	registers := c.request("variables", map[string]int{"variablesReference": registersReference})["variables"].([]interface{})
+	if len(registers) != 31 {
+		t.Fatalf("Expected 31 registers, got %d.", len(registers))
+	}
+	sp := registers[28].(map[string]interface{})
+	if sp["name"] != "X28 (SP)" || sp["value"] != "65536 (0x10000)" {
+		t.Errorf("Incorrect stack pointer %v.", sp)
+	}
+	x30 := registers[30].(map[string]interface{})
+	if x30["value"] != "8 (0x8)" || x30["memoryReference"] != "0x8" {
+		t.Errorf("Incorrect link register %v.", x30)
+	}
+
+	flags := c.request("variables", map[string]int{"variablesReference": flagsReference})["variables"].([]interface{})
+	if len(flags) != 4 || flags[1].(map[string]interface{})["name"] != "Z" {
+		t.Errorf("Incorrect flags %v.", flags)
+	}
+
+	memory := c.request("readMemory", map[string]interface{}{"memoryReference": "0xFFF8", "count": 16})
+	if memory["data"] != "AAAAAAAAAAA=" || memory["unreadableBytes"] != float64(8) {
+		t.Errorf("Incorrect memory %v.", memory)
+	}
+}
+
+func TestBreakpointOnLabel(t *testing.T) {
+	c := newClient(t, program)
+	body := c.start(false, 6, 20)
+
+	breakpoints := body["breakpoints"].([]interface{})
+	first := breakpoints[0].(map[string]interface{})
+	if first["verified"] != true || first["line"] != float64(7) {
+		t.Errorf("Expected breakpoint to move to line 7, got %v.", first)
+	}
+	if second := breakpoints[1].(map[string]interface{}); second["verified"] != false {
+		t.Errorf("Expected breakpoint past the end to be unverified, got %v.", second)
+	}
+	if _, lines := c.stopped(); !equalLines(lines, []int{7, 3}) {
+		t.Errorf("Expected to stop at [7 3], got %v.", lines)
+	}
+}
+
+func TestException(t *testing.T) {
+	c := newClient(t, "ADDI X0, XZR, #1\nLDUR X1, [XZR, #-8]")
+	c.start(false)
+
+	stopped := c.wait("event", "stopped")["body"].(map[string]interface{})
+	if stopped["reason"] != "exception" || stopped["text"] != "2: Memory access of 8 bytes at address -8 is out of bounds." {
+		t.Errorf("Incorrect exception %v.", stopped)
+	}
+	c.request("continue", map[string]int{"threadId": threadID})
+	c.wait("event", "terminated")
+}
+
+func TestTerminate(t *testing.T) {
+	c := newClient(t, "loop:\n    B loop")
+	c.start(false)
+
+	// the running program and the request both end the session. count the
+	// terminated events until the program has exited and a later request
+	// has been answered.
+	c.send("terminate", nil)
+	terminated, exited := 0, false
+	for m := range c.messages {
+		if m["type"] == "event" && m["event"] == "terminated" {
+			terminated++
+		}
+		if m["type"] == "event" && m["event"] == "exited" {
+			exited = true
+			c.send("threads", nil)
+		}
+		if m["type"] == "response" && m["command"] == "threads" {
+			break
+		}
+	}
+	if !exited || terminated != 1 {
+		t.Errorf("Expected exited and one terminated, got exited %v and %d terminated.", exited, terminated)
+	}
+}
+
+func TestLaunchErrors(t *testing.T) {
+	c := newClient(t, "B nowhere")
+	c.request("initialize", map[string]interface{}{})
+	c.send("launch", map[string]interface{}{"program": c.path})
+	res := c.wait("response", "launch")
+	if res["success"] != false || res["message"] != "Cannot assemble program.\nprogram.legv8:1:3: Label 'nowhere' is not defined." {
+		t.Errorf("Incorrect launch failure %v.", res)
+	}
+}
+
+func TestPause(t *testing.T) {
+	c := newClient(t, "loop:\nB loop")
+	c.start(false)
+
+	c.request("pause", map[string]int{"threadId": threadID})
+	if reason, lines := c.stopped(); reason != "pause" || !equalLines(lines, []int{2}) {
+		t.Errorf("Expected pause at [2], got %s at %v.", reason, lines)
+	}
+}
@@ -0,0 +1,220 @@
+package dap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"server/isa"
+	"server/simulator"
+)
+
+// references of the variable scopes
+const (
+	registersReference = iota + 1
+	flagsReference
+	floatReference
+)
+
+type variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	VariablesReference int    `json:"variablesReference"`
+	MemoryReference    string `json:"memoryReference,omitempty"`
+}
+
+func (s *Session) handleStackTrace(r *request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.machine == nil {
+		return s.out.fail(r, "No program is running.")
+	}
+
+	source := map[string]interface{}{"name": filepath.Base(s.path), "path": s.path}
+	stackFrame := func(id int, name string, line int) map[string]interface{} {
+		return map[string]interface{}{"id": id, "name": name, "source": source, "line": s.clientLine(line), "column": s.clientLine(0)}
+	}
+
+	// the innermost frame is where the program is stopped; each outer frame
+	// is stopped at the BL that made the call inside it
+	frames := []map[string]interface{}{}
+	line := s.machine.Line()
+	for i := len(s.frames) - 1; i >= 0; i-- {
+		frames = append(frames, stackFrame(len(frames)+1, s.function(s.frames[i].entry), line))
+		line = s.machine.LineAt(s.frames[i].call)
+	}
+	frames = append(frames, stackFrame(len(frames)+1, "main", line))
+
+	return s.out.respond(r, map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)})
+}
+
+// function returns the name of the label at an address.
+func (s *Session) function(address uint64) string {
+	for _, label := range s.program.Labels() {
+		if uint64(label.Index)*4 == address {
+			return label.Name
+		}
+	}
+	return fmt.Sprintf("0x%X", address)
+}
+
+func (s *Session) handleScopes(r *request) error {
+	scopes := []map[string]interface{}{
+		{"name": "Registers", "variablesReference": registersReference, "expensive": false},
+		{"name": "Flags", "variablesReference": flagsReference, "expensive": false},
+		{"name": "Floating Point", "variablesReference": floatReference, "expensive": false},
+	}
+	return s.out.respond(r, map[string]interface{}{"scopes": scopes})
+}
+
+func (s *Session) handleVariables(r *request) error {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := json.Unmarshal(r.Arguments, &args); err != nil {
+		return s.out.fail(r, "Invalid variables arguments.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.machine == nil {
+		return s.out.fail(r, "No program is running.")
+	}
+	m := s.machine
+
+	variables := []variable{}
+	switch args.VariablesReference {
+	case registersReference:
+		for i := 0; i < isa.XZR; i++ {
+			variables = append(variables, s.register(i))
+		}
+	case flagsReference:
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{{"N", m.N}, {"Z", m.Z}, {"C", m.C}, {"V", m.V}} {
+			variables = append(variables, variable{Name: f.name, Value: strconv.Itoa(simulator.Flag(f.set))})
+		}
+	case floatReference:
+		for i := range m.F {
+			variables = append(variables, variable{Name: fmt.Sprintf("S%d", i), Value: fmt.Sprint(m.Single(i))})
+		}
+		for i := range m.F {
+			variables = append(variables, variable{Name: fmt.Sprintf("D%d", i), Value: fmt.Sprint(m.Double(i))})
+		}
+	}
+
+	return s.out.respond(r, map[string]interface{}{"variables": variables})
+}
+
+// register describes an integer register, with a memory reference when its
+// value is an address in memory.
+func (s *Session) register(n int) variable {
+	value := s.machine.X[n]
+	name := fmt.Sprintf("X%d", n)
+	switch n {
+	case isa.SP:
+		name += " (SP)"
+	case isa.FP:
+		name += " (FP)"
+	case isa.LR:
+		name += " (LR)"
+	}
+
+	v := variable{Name: name, Value: fmt.Sprintf("%d (0x%X)", int64(value), value)}
+	if value < uint64(len(s.machine.Memory)) {
+		v.MemoryReference = fmt.Sprintf("0x%X", value)
+	}
+	return v
+}
+
+// handleEvaluate looks up registers and flags by name, for the watch panel
+// and hovers in the editor.
+func (s *Session) handleEvaluate(r *request) error {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(r.Arguments, &args); err != nil {
+		return s.out.fail(r, "Invalid evaluate arguments.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.machine == nil {
+		return s.out.fail(r, "No program is running.")
+	}
+	m := s.machine
+
+	name := strings.ToUpper(strings.TrimSpace(args.Expression))
+	var v variable
+	if n, ok := isa.IntegerRegister(name); ok {
+		if n == isa.XZR {
+			v = variable{Value: "0 (0x0)"}
+		} else {
+			v = s.register(n)
+		}
+	} else if n, ok := isa.FloatRegister(name); ok {
+		if name[0] == 'S' {
+			v.Value = fmt.Sprint(m.Single(n))
+		} else {
+			v.Value = fmt.Sprint(m.Double(n))
+		}
+	} else if set, ok := map[string]bool{"N": m.N, "Z": m.Z, "C": m.C, "V": m.V}[name]; ok {
+		v.Value = strconv.Itoa(simulator.Flag(set))
+	} else {
+		return s.out.fail(r, fmt.Sprintf("'%s' is not a register or flag.", args.Expression))
+	}
+
+	body := map[string]interface{}{"result": v.Value, "variablesReference": 0}
+	if v.MemoryReference != "" {
+		body["memoryReference"] = v.MemoryReference
+	}
+	return s.out.respond(r, body)
+}
+
+func (s *Session) handleReadMemory(r *request) error {
+	var args struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int64  `json:"offset"`
+		Count           int64  `json:"count"`
+	}
+	if err := json.Unmarshal(r.Arguments, &args); err != nil {
+		return s.out.fail(r, "Invalid readMemory arguments.")
+	}
+	base, err := strconv.ParseInt(args.MemoryReference, 0, 64)
+	if err != nil {
+		return s.out.fail(r, fmt.Sprintf("'%s' is not a memory address.", args.MemoryReference))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.machine == nil {
+		return s.out.fail(r, "No program is running.")
+	}
+	memory := s.machine.Memory
+
+	// bytes outside of memory are reported as unreadable rather than failing
+	start := base + args.Offset
+	end := start + args.Count
+	if start < 0 {
+		start = 0
+	}
+	if end > int64(len(memory)) {
+		end = int64(len(memory))
+	}
+	data := []byte{}
+	if start < end {
+		data = memory[start:end]
+	}
+	unreadable := args.Count - int64(len(data))
+
+	body := map[string]interface{}{
+		"address":         fmt.Sprintf("0x%X", start),
+		"data":            base64.StdEncoding.EncodeToString(data),
+		"unreadableBytes": unreadable,
+	}
+	return s.out.respond(r, body)
+}
@@ -0,0 +1,99 @@
+package disassembler
+
+import (
+	"fmt"
+	"strings"
+
+	"server/isa"
+)
+
+// decode returns the operands of a word as assembly text. Branch targets are
+// returned separately as an offset in instructions.
+func decode(info *isa.Instruction, word uint32) ([]string, int64) {
+	rd := word & 31
+	rn := (word >> 5) & 31
+	rm := (word >> 16) & 31
+	shamt := (word >> 10) & 63
+
+	switch info.Format {
+	case isa.R:
+		switch info.Mnemonic {
+		case "LSL", "LSR":
+			return []string{x(rd), x(rn), fmt.Sprintf("#%d", shamt)}, 0
+		case "BR", "BLR":
+			return []string{x(rn)}, 0
+		case "FCMPS", "FCMPD":
+			prefix := float(info.Mnemonic)
+			return []string{f(prefix, rn), f(prefix, rm)}, 0
+		case "PRNT":
+			return []string{x(rd)}, 0
+		case "RET":
+			if rn != isa.LR {
+				return []string{x(rn)}, 0
+			}
+			return []string{}, 0
+		case "PRNL", "DUMP", "HALT":
+			return []string{}, 0
+		}
+		if prefix := float(info.Mnemonic); prefix != "" {
+			return []string{f(prefix, rd), f(prefix, rn), f(prefix, rm)}, 0
+		}
+		return []string{x(rd), x(rn), x(rm)}, 0
+	case isa.I:
+		return []string{x(rd), x(rn), fmt.Sprintf("#%d", (word>>10)&(1<<12-1))}, 0
+	case isa.D:
+		rt := x(rd)
+		if prefix := float(info.Mnemonic); prefix != "" {
+			rt = f(prefix, rd)
+		}
+		return []string{rt, fmt.Sprintf("[%s, #%d]", x(rn), signExtend(word>>12, 9))}, 0
+	case isa.B:
+		return []string{}, signExtend(word, 26)
+	case isa.CB:
+		offset := signExtend(word>>5, 19)
+		if strings.HasPrefix(info.Mnemonic, "B.") {
+			return []string{}, offset
+		}
+		return []string{x(rd)}, offset
+	case isa.IW:
+		return []string{x(rd), fmt.Sprintf("#%d", (word>>5)&(1<<16-1)), fmt.Sprintf("LSL #%d", 16*((word>>21)&3))}, 0
+	}
+	return []string{}, 0
+}
+
+// signExtend interprets the low bits of value as a two's complement number.
+func signExtend(value uint32, bits int) int64 {
+	value &= 1<<bits - 1
+	if value&(1<<(bits-1)) != 0 {
+		return int64(value) - 1<<bits
+	}
+	return int64(value)
+}
+
+// x returns the name of an integer register.
+func x(number uint32) string {
+	if number == 31 {
+		return "XZR"
+	}
+	return fmt.Sprintf("X%d", number)
+}
+
+// f returns the name of a floating-point register.
+func f(prefix string, number uint32) string {
+	return fmt.Sprintf("%s%d", prefix, number)
+}
+
+// float returns the register prefix of a floating-point instruction, S or D,
+// or the empty string if it does not operate on floating-point registers.
+func float(mnemonic string) string {
+	switch mnemonic {
+	case "LDURS", "STURS":
+		return "S"
+	case "LDURD", "STURD":
+		return "D"
+	}
+	if strings.HasPrefix(mnemonic, "F") {
+		return mnemonic[len(mnemonic)-1:]
+	}
+	return ""
+}
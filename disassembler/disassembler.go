@@ -0,0 +1,132 @@
+// Package disassembler decodes 32-bit LEGv8 machine code back into assembly
+// source, synthesizing labels for branch targets.
+package disassembler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"server/isa"
+)
+
+// Error is a word that could not be disassembled.
+type Error struct {
+	Address uint64
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%08X: %s", e.Address, e.Message)
+}
+
+// Instruction is a single decoded word.
+type Instruction struct {
+	Address  uint64
+	Encoding uint32
+
+	// Mnemonic is empty when the word does not encode a known instruction.
+	Mnemonic string
+	Operands []string
+}
+
+// String returns the instruction as a line of assembly.
+func (i *Instruction) String() string {
+	if i.Mnemonic == "" {
+		return fmt.Sprintf("// unknown encoding %08X", i.Encoding)
+	}
+	if len(i.Operands) == 0 {
+		return i.Mnemonic
+	}
+	return i.Mnemonic + " " + strings.Join(i.Operands, ", ")
+}
+
+// Disassembly is the decoded form of a program.
+type Disassembly struct {
+	Instructions []*Instruction
+
+	// Labels maps the address of each branch target to its synthesized name.
+	Labels map[uint64]string
+}
+
+// Disassemble decodes words laid out from address zero. Every word produces
+// an instruction, even when errors are reported for it.
+func Disassemble(words []uint32) (*Disassembly, []*Error) {
+	d := &Disassembly{Instructions: []*Instruction{}, Labels: map[uint64]string{}}
+	errs := []*Error{}
+
+	end := uint64(len(words)) * 4
+	targets := map[*Instruction]uint64{}
+	for i, word := range words {
+		instruction := &Instruction{Address: uint64(i) * 4, Encoding: word}
+		d.Instructions = append(d.Instructions, instruction)
+
+		info, ok := isa.Decode(word)
+		if !ok {
+			errs = append(errs, &Error{instruction.Address, fmt.Sprintf("Unknown instruction encoding %08X.", word)})
+			continue
+		}
+		instruction.Mnemonic = info.Mnemonic
+		var offset int64
+		instruction.Operands, offset = decode(info, word)
+
+		if info.Format != isa.B && info.Format != isa.CB {
+			continue
+		}
+		target := uint64(int64(instruction.Address) + 4*offset)
+		if int64(instruction.Address)+4*offset < 0 || target > end {
+			errs = append(errs, &Error{instruction.Address, fmt.Sprintf("Branch target %d is outside the program.", int64(instruction.Address)+4*offset)})
+			instruction.Operands = append(instruction.Operands, fmt.Sprintf("#%d", offset))
+			continue
+		}
+		targets[instruction] = target
+		d.Labels[target] = ""
+	}
+
+	// name labels in address order so the output reads top to bottom
+	addresses := make([]uint64, 0, len(d.Labels))
+	for address := range d.Labels {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	for i, address := range addresses {
+		d.Labels[address] = fmt.Sprintf("L%d", i+1)
+	}
+	for instruction, target := range targets {
+		instruction.Operands = append(instruction.Operands, d.Labels[target])
+	}
+
+	return d, errs
+}
+
+// Source returns the program as assembly, with each synthesized label on its
+// own line before the instruction it names.
+func (d *Disassembly) Source() string {
+	var b strings.Builder
+	for _, instruction := range d.Instructions {
+		if label, ok := d.Labels[instruction.Address]; ok {
+			fmt.Fprintf(&b, "%s:\n", label)
+		}
+		fmt.Fprintf(&b, "    %s\n", instruction)
+	}
+	if label, ok := d.Labels[uint64(len(d.Instructions))*4]; ok {
+		fmt.Fprintf(&b, "%s:\n", label)
+	}
+	return b.String()
+}
+
+// Listing returns the program with the address and encoding of each
+// instruction beside it, in the same layout as the assembler's listing.
+func (d *Disassembly) Listing() string {
+	var b strings.Builder
+	for _, instruction := range d.Instructions {
+		if label, ok := d.Labels[instruction.Address]; ok {
+			fmt.Fprintf(&b, "%18s%s:\n", "", label)
+		}
+		fmt.Fprintf(&b, "%08X  %08X  %s\n", instruction.Address, instruction.Encoding, instruction)
+	}
+	if label, ok := d.Labels[uint64(len(d.Instructions))*4]; ok {
+		fmt.Fprintf(&b, "%18s%s:\n", "", label)
+	}
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package disassembler_test
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"server/assembler"
+	"server/disassembler"
+	"server/isa"
+	"server/languageserver"
+	"server/parser"
+)
+
+func TestDisassemble(t *testing.T) {
+	inputs := []struct {
+		words    []uint32
+		expected string
+	}{
+		{[]uint32{0x8B150289}, "    ADD X9, X20, X21\n"},
+		{[]uint32{0xF85F8381}, "    LDUR X1, [X28, #-8]\n"},
+		{[]uint32{0x91000529}, "    ADDI X9, X9, #1\n"},
+		{[]uint32{0xD2A01FE9}, "    MOVZ X9, #255, LSL #16\n"},
+		{[]uint32{0xD61F03C0}, "    BR X30\n"},
+		{[]uint32{0xD65F03C0}, "    RET\n"},
+		{[]uint32{0xD65F00A0}, "    RET X5\n"},
+		{[]uint32{0xAA0203E1}, "    ORR X1, XZR, X2\n"},
+		{[]uint32{0xD3600C41}, "    LSL X1, X2, #3\n"},
+		{[]uint32{0x9AC20C20}, "    UDIV X0, X1, X2\n"},
+		{[]uint32{0x1E232841}, "    FADDS S1, S2, S3\n"},
+		{[]uint32{0xFC408041}, "    LDURD D1, [X2, #8]\n"},
+		{[]uint32{0xFFA00000}, "    PRNT X0\n"},
+		{[]uint32{0xFFE00000}, "    HALT\n"},
+		{[]uint32{0x14000000}, "L1:\n    B L1\n"},
+		{[]uint32{0xB4000021}, "    CBZ X1, L1\nL1:\n"},
+		{[]uint32{0xFFE00000, 0x54FFFFE1}, "L1:\n    HALT\n    B.NE L1\n"},
+		{[]uint32{0x94000002, 0xB4FFFFE0, 0xD61F03C0}, "L1:\n    BL L2\n    CBZ X0, L1\nL2:\n    BR X30\n"},
+	}
+
+	for _, in := range inputs {
+		d, errs := disassembler.Disassemble(in.words)
+		if len(errs) > 0 {
+			t.Errorf("Unexpected errors %v. Input: %08X", errs, in.words)
+			continue
+		}
+		if source := d.Source(); source != in.expected {
+			t.Errorf("Expected %q, got %q. Input: %08X", in.expected, source, in.words)
+		}
+	}
+}
+
+func TestDisassembleErrors(t *testing.T) {
+	inputs := []struct {
+		words   []uint32
+		message string
+	}{
+		{[]uint32{0x00000000}, "00000000: Unknown instruction encoding 00000000."},
+		{[]uint32{0x5400000E}, "00000000: Unknown instruction encoding 5400000E."},
+		{[]uint32{0x14000005}, "00000000: Branch target 20 is outside the program."},
+		{[]uint32{0xFFE00000, 0x17FFFFFE}, "00000004: Branch target -4 is outside the program."},
+	}
+
+	for _, in := range inputs {
+		_, errs := disassembler.Disassemble(in.words)
+		if len(errs) != 1 || errs[0].Error() != in.message {
+			t.Errorf("Expected error %q, got %v. Input: %08X", in.message, errs, in.words)
+		}
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	words, err := disassembler.ParseHex("8B150289 0x91000529\n// halt\nffe00000 // end\n")
+	if err != nil {
+		t.Fatalf("Unexpected error %v.", err)
+	}
+	if fmt.Sprintf("%08X", words) != "[8B150289 91000529 FFE00000]" {
+		t.Errorf("Incorrect words %08X.", words)
+	}
+
+	if _, err := disassembler.ParseHex("8B15028G"); err == nil {
+		t.Errorf("Expected an error for an invalid word.")
+	}
+	if words, err := disassembler.ParseBinary([]byte{0x89, 0x02, 0x15, 0x8B}); err != nil || len(words) != 1 || words[0] != 0x8B150289 {
+		t.Errorf("Expected little-endian word 8B150289, got %08X, %v.", words, err)
+	}
+	if _, err := disassembler.ParseBinary([]byte{0x8B, 0x15}); err == nil {
+		t.Errorf("Expected an error for a partial word.")
+	}
+}
+
+// operand returns a random value for an operand named in an instruction's syntax.
+func operand(r *rand.Rand, name string, labels int) string {
+	switch name {
+	case "ALU_immediate":
+		return fmt.Sprint(r.Intn(1 << 12))
+	case "DT_address":
+		return fmt.Sprint(r.Intn(1<<9) - 1<<8)
+	case "shamt":
+		return fmt.Sprint(r.Intn(1 << 6))
+	case "MOV_immediate":
+		return fmt.Sprint(r.Intn(1 << 16))
+	case "shift":
+		return fmt.Sprint(16 * r.Intn(4))
+	case "label":
+		return fmt.Sprintf("l%d", r.Intn(labels))
+	}
+	switch name[0] {
+	case 'R':
+		if n := r.Intn(32); n < 31 {
+			return fmt.Sprintf("X%d", n)
+		}
+		return "XZR"
+	case 'S', 'D':
+		return fmt.Sprintf("%c%d", name[0], r.Intn(32))
+	}
+	return name
+}
+
+// TestRoundTrip assembles random uses of every instruction, disassembles the
+// machine code, and checks the disassembly assembles to the same words.
+func TestRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const labels = 3
+
+	for mnemonic := range parser.KeywordInstructionTypes {
+		syntax := languageserver.Instructions[mnemonic].Syntax
+
+		lines := []string{}
+		for i := 0; i < 50; i++ {
+			lines = append(lines, isa.MapOperands(syntax, func(name string) string {
+				return operand(r, name, labels)
+			}))
+		}
+		for i := 0; i < labels; i++ {
+			at := r.Intn(len(lines) + 1)
+			lines = append(lines[:at], append([]string{fmt.Sprintf("l%d:", i)}, lines[at:]...)...)
+		}
+		source := strings.Join(lines, "\n")
+
+		original, errs := assembler.Assemble(parser.ParseProgram(source))
+		if len(errs) > 0 {
+			t.Errorf("Unexpected assembler errors %v. Input: %q", errs, source)
+			continue
+		}
+		words := make([]uint32, len(original.Words))
+		for i, word := range original.Words {
+			words[i] = word.Encoding
+		}
+
+		d, disassemblyErrs := disassembler.Disassemble(words)
+		if len(disassemblyErrs) > 0 {
+			t.Errorf("Unexpected disassembler errors %v. Input: %q", disassemblyErrs, source)
+			continue
+		}
+		if _, ok := isa.PseudoInstructions[mnemonic]; !ok && d.Instructions[0].Mnemonic != mnemonic {
+			t.Errorf("Expected %s, got %s.", mnemonic, d.Instructions[0].Mnemonic)
+		}
+
+		reassembled, errs := assembler.Assemble(parser.ParseProgram(d.Source()))
+		if len(errs) > 0 {
+			t.Errorf("Unexpected errors reassembling %v. Input: %q", errs, d.Source())
+			continue
+		}
+		for i, word := range reassembled.Words {
+			if word.Encoding != words[i] {
+				t.Errorf("Expected %08X, got %08X reassembling %q.", words[i], word.Encoding, d.Instructions[i])
+			}
+		}
+	}
+}
+
+func TestListing(t *testing.T) {
+	d, _ := disassembler.Disassemble([]uint32{0x91000529, 0xB5FFFFE9})
+	expected := "" +
+		"                  L1:\n" +
+		"00000000  91000529  ADDI X9, X9, #1\n" +
+		"00000004  B5FFFFE9  CBNZ X9, L1\n"
+	if listing := d.Listing(); listing != expected {
+		t.Errorf("Incorrect listing %q.", listing)
+	}
+}
@@ -0,0 +1,42 @@
+package disassembler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseHex reads whitespace-separated hexadecimal words, as written by the
+// assembler's hex output. Words may have a 0x prefix and lines may end in a
+// // comment.
+func ParseHex(text string) ([]uint32, error) {
+	words := []uint32{}
+	for i, line := range strings.Split(text, "\n") {
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = line[:comment]
+		}
+		for _, field := range strings.Fields(line) {
+			digits := strings.TrimPrefix(strings.TrimPrefix(field, "0x"), "0X")
+			word, err := strconv.ParseUint(digits, 16, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: '%s' is not a 32-bit hexadecimal word", i+1, field)
+			}
+			words = append(words, uint32(word))
+		}
+	}
+	return words, nil
+}
+
+// ParseBinary reads raw little-endian 32-bit words, as written by the
+// assembler's binary output.
+func ParseBinary(data []byte) ([]uint32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("binary is %d bytes long, which is not a whole number of words", len(data))
+	}
+	words := make([]uint32, len(data)/4)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(data[4*i:])
+	}
+	return words, nil
+}
@@ -0,0 +1,85 @@
+// Package format implements the fmt command, which lays out LEGv8 source
+// files the same way the language server formats documents.
+package format
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"server/languageserver"
+	"server/lint"
+)
+
+// Exit codes of the fmt command.
+const (
+	ExitClean = 0
+	// ExitUnformatted is returned with -l when any file is not formatted, so
+	// that the command can be used as a pre-commit check.
+	ExitUnformatted = 1
+	ExitUsage       = 2
+)
+
+// Main runs the fmt command with the given arguments, returning its exit
+// code. Formatted files are written to stdout unless -w or -l is given.
+func Main(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: server fmt [-l] [-w] [-tabwidth n] [-tabs] [file | directory | glob]...")
+		flags.PrintDefaults()
+	}
+	list := flags.Bool("l", false, "list files whose formatting differs and exit nonzero if there are any")
+	write := flags.Bool("w", false, "write the result to each file instead of stdout")
+	tabWidth := flags.Int("tabwidth", languageserver.DefaultTabWidth, "indent instructions by `n` columns")
+	useTabs := flags.Bool("tabs", false, "indent with tabs instead of spaces")
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *tabWidth <= 0 {
+		fmt.Fprintf(stderr, "fmt: tab width must be positive, got %d\n", *tabWidth)
+		return ExitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	paths, err := lint.Expand(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "fmt: %v\n", err)
+		return ExitUsage
+	}
+
+	options := languageserver.FormatOptions{TabWidth: *tabWidth, UseTabs: *useTabs}
+	unformatted := false
+	for _, path := range paths {
+		source, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "fmt: %v\n", err)
+			return ExitUsage
+		}
+		formatted := languageserver.Format(string(source), options)
+		changed := formatted != string(source)
+
+		if *list && changed {
+			fmt.Fprintln(stdout, path)
+			unformatted = true
+		}
+		if *write && changed {
+			if err := ioutil.WriteFile(path, []byte(formatted), 0644); err != nil {
+				fmt.Fprintf(stderr, "fmt: %v\n", err)
+				return ExitUsage
+			}
+		}
+		if !*list && !*write {
+			io.WriteString(stdout, formatted)
+		}
+	}
+
+	if unformatted {
+		return ExitUnformatted
+	}
+	return ExitClean
+}
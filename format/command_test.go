@@ -0,0 +1,56 @@
+package format
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	messy := filepath.Join(dir, "messy.legv8")
+	tidy := filepath.Join(dir, "tidy.s")
+	if err := ioutil.WriteFile(messy, []byte("loop:\nsubi X1,X1,#1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(tidy, []byte("    HALT\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	crlf := filepath.Join(dir, "crlf.legv8")
+	if err := ioutil.WriteFile(crlf, []byte("loop:\r\n    HALT\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := Main([]string{messy}, &stdout, &stderr); code != ExitClean || stdout.String() != "loop:\n    SUBI X1, X1, #1\n" {
+		t.Errorf("Expected formatted output, got %d %q %q.", code, stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	if code := Main([]string{"-l", dir}, &stdout, &stderr); code != ExitUnformatted || strings.TrimSpace(stdout.String()) != messy {
+		t.Errorf("Expected only %s to be listed, got %d %q.", messy, code, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := Main([]string{"-w", "-tabwidth", "2", messy}, &stdout, &stderr); code != ExitClean || stdout.Len() != 0 {
+		t.Errorf("Expected nothing to be written to stdout, got %d %q.", code, stdout.String())
+	}
+	if source, _ := ioutil.ReadFile(messy); string(source) != "loop:\n  SUBI X1, X1, #1\n" {
+		t.Errorf("Expected the file to be formatted in place, got %q.", source)
+	}
+
+	if code := Main([]string{"-tabwidth", "0", messy}, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("Expected usage error for a tab width of 0, got %d.", code)
+	}
+	if code := Main([]string{}, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("Expected usage error without files, got %d.", code)
+	}
+}
@@ -0,0 +1,60 @@
+package isa
+
+// opcodes maps each format to the instructions sharing each opcode. R and D
+// format opcodes are both 11 bits wide and are kept in the same table.
+var opcodes = map[Format]map[uint16][]*Instruction{}
+
+// conditionSuffixes maps each B.cond condition code to its suffix.
+var conditionSuffixes = map[uint8]string{}
+
+// decodeOrder is the order opcodes are matched in, shortest first. No opcode
+// is a prefix of a longer one, so the first match is the only match.
+var decodeOrder = []Format{B, CB, I, IW, R}
+
+// indexOpcodes builds the decoding tables once Instructions is complete.
+func indexOpcodes() {
+	for _, instruction := range Instructions {
+		format := instruction.Format
+		if format == D {
+			format = R
+		}
+		if opcodes[format] == nil {
+			opcodes[format] = map[uint16][]*Instruction{}
+		}
+		opcodes[format][instruction.Opcode] = append(opcodes[format][instruction.Opcode], instruction)
+	}
+	for suffix, code := range ConditionCodes {
+		conditionSuffixes[code] = suffix
+	}
+}
+
+// Decode finds the instruction a machine word encodes.
+func Decode(word uint32) (*Instruction, bool) {
+	for _, format := range decodeOrder {
+		candidates := opcodes[format][uint16(word>>(32-format.OpcodeWidth()))]
+		if len(candidates) == 0 {
+			continue
+		}
+
+		switch {
+		case len(candidates) == 1:
+			return candidates[0], true
+		case format == CB:
+			// B.cond instructions share an opcode and differ in the Rt field
+			suffix, ok := conditionSuffixes[uint8(word&31)]
+			if !ok {
+				return nil, false
+			}
+			return Instructions["B."+suffix], true
+		default:
+			// R-format instructions sharing an opcode differ in shamt
+			for _, candidate := range candidates {
+				if uint32(candidate.Shamt) == (word>>10)&63 {
+					return candidate, true
+				}
+			}
+			return nil, false
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,170 @@
+// Package isa describes the LEGv8 instruction set: the encoding format and
+// opcode of each instruction, branch condition codes and register numbers.
+// It is shared by the language server, assembler, disassembler and simulator.
+package isa
+
+// Format is the machine encoding format of an instruction.
+type Format int8
+
+const (
+	R Format = iota + 1
+	I
+	D
+	B
+	CB
+	IW
+)
+
+func (f Format) String() string {
+	switch f {
+	case R:
+		return "R"
+	case I:
+		return "I"
+	case D:
+		return "D"
+	case B:
+		return "B"
+	case CB:
+		return "CB"
+	case IW:
+		return "IW"
+	}
+	return "UNKNOWN"
+}
+
+// OpcodeWidth returns the number of bits in the opcode of the format.
+func (f Format) OpcodeWidth() int {
+	switch f {
+	case I:
+		return 10
+	case B:
+		return 6
+	case CB:
+		return 8
+	case IW:
+		return 9
+	}
+	return 11
+}
+
+// Instruction is the encoding of a single machine instruction.
+type Instruction struct {
+	Mnemonic string
+	Format   Format
+	Opcode   uint16
+
+	// Shamt is the fixed value of the shamt field for R-format instructions
+	// that share an opcode, such as SDIV and UDIV.
+	Shamt uint8
+}
+
+// Instructions maps each mnemonic to its encoding. Pseudo-instructions are
+// not listed; see PseudoInstructions.
+var Instructions map[string]*Instruction
+
+// ConditionCodes maps each B.cond suffix to the value encoded in the Rt field.
+var ConditionCodes = map[string]uint8{
+	"EQ": 0,
+	"NE": 1,
+	"HS": 2,
+	"LO": 3,
+	"MI": 4,
+	"PL": 5,
+	"VS": 6,
+	"VC": 7,
+	"HI": 8,
+	"LS": 9,
+	"GE": 10,
+	"LT": 11,
+	"GT": 12,
+	"LE": 13,
+}
+
+var instructionTable = []*Instruction{
+	// R-format. LSL and LSR take an immediate but encode the shift in shamt.
+	{"ADD", R, 0x458, 0},
+	{"ADDS", R, 0x558, 0},
+	{"AND", R, 0x450, 0},
+	{"ANDS", R, 0x750, 0},
+	{"EOR", R, 0x650, 0},
+	{"ORR", R, 0x550, 0},
+	{"SUB", R, 0x658, 0},
+	{"SUBS", R, 0x758, 0},
+	{"MUL", R, 0x4D8, 0x1F},
+	{"SMULH", R, 0x4DA, 0},
+	{"UMULH", R, 0x4DE, 0},
+	{"SDIV", R, 0x4D6, 0x02},
+	{"UDIV", R, 0x4D6, 0x03},
+	{"FADDS", R, 0x0F1, 0x0A},
+	{"FADDD", R, 0x0F3, 0x0A},
+	{"FSUBS", R, 0x0F1, 0x0E},
+	{"FSUBD", R, 0x0F3, 0x0E},
+	{"FMULS", R, 0x0F1, 0x02},
+	{"FMULD", R, 0x0F3, 0x02},
+	{"FDIVS", R, 0x0F1, 0x06},
+	{"FDIVD", R, 0x0F3, 0x06},
+	{"FCMPS", R, 0x0F1, 0x08},
+	{"FCMPD", R, 0x0F3, 0x08},
+	{"LDURS", D, 0x5E2, 0},
+	{"LDURD", D, 0x7E2, 0},
+	{"STURS", D, 0x5E0, 0},
+	{"STURD", D, 0x7E0, 0},
+	{"LSL", R, 0x69B, 0},
+	{"LSR", R, 0x69A, 0},
+	{"BR", R, 0x6B0, 0},
+	{"BLR", R, 0x6B1, 0},
+	{"RET", R, 0x6B2, 0},
+
+	// I-format
+	{"ADDI", I, 0x244, 0},
+	{"ADDIS", I, 0x2C4, 0},
+	{"ANDI", I, 0x248, 0},
+	{"ANDIS", I, 0x3C8, 0},
+	{"EORI", I, 0x348, 0},
+	{"ORRI", I, 0x2C8, 0},
+	{"SUBI", I, 0x344, 0},
+	{"SUBIS", I, 0x3C4, 0},
+
+	// D-format
+	{"LDUR", D, 0x7C2, 0},
+	{"LDURB", D, 0x1C2, 0},
+	{"LDURH", D, 0x3C2, 0},
+	{"LDURSW", D, 0x5C4, 0},
+	{"LDXR", D, 0x642, 0},
+	{"STUR", D, 0x7C0, 0},
+	{"STURB", D, 0x1C0, 0},
+	{"STURH", D, 0x3C0, 0},
+	{"STURW", D, 0x5C0, 0},
+	{"STXR", D, 0x640, 0},
+
+	// B-format
+	{"B", B, 0x05, 0},
+	{"BL", B, 0x25, 0},
+
+	// CB-format
+	{"CBZ", CB, 0xB4, 0},
+	{"CBNZ", CB, 0xB5, 0},
+
+	// IW-format
+	{"MOVZ", IW, 0x1A5, 0},
+	{"MOVK", IW, 0x1E5, 0},
+
+	// simulator instructions
+	{"PRNT", R, 0x7FD, 0},
+	{"PRNL", R, 0x7FC, 0},
+	{"DUMP", R, 0x7FE, 0},
+	{"HALT", R, 0x7FF, 0},
+}
+
+func init() {
+	Instructions = make(map[string]*Instruction)
+	for _, instruction := range instructionTable {
+		Instructions[instruction.Mnemonic] = instruction
+	}
+	for suffix := range ConditionCodes {
+		mnemonic := "B." + suffix
+		Instructions[mnemonic] = &Instruction{Mnemonic: mnemonic, Format: CB, Opcode: 0x54}
+	}
+	indexOpcodes()
+}
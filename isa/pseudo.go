@@ -0,0 +1,92 @@
+package isa
+
+import (
+	"strings"
+)
+
+// Pseudo is a pseudo-instruction, which is assembled as one or more other
+// instructions.
+type Pseudo struct {
+	Mnemonic string
+	Syntax   string
+
+	// Expansion is the instructions assembled in place of the
+	// pseudo-instruction, written with the operand names from Syntax. A label
+	// operand in the place of an immediate stands for the label's address.
+	Expansion []string
+}
+
+// PseudoInstructions maps each pseudo-instruction mnemonic to its expansion.
+var PseudoInstructions = map[string]*Pseudo{
+	"MOV":   {"MOV", "MOV Rd, Rm", []string{"ORR Rd, XZR, Rm"}},
+	"CMP":   {"CMP", "CMP Rn, Rm", []string{"SUBS XZR, Rn, Rm"}},
+	"CMPI":  {"CMPI", "CMPI Rn, #ALU_immediate", []string{"SUBIS XZR, Rn, #ALU_immediate"}},
+	"LDA":   {"LDA", "LDA Rd, [Rn, #ALU_immediate]", []string{"ADDI Rd, Rn, #ALU_immediate"}},
+	"ADR":   {"ADR", "ADR Rd, label", []string{"ADDI Rd, XZR, #label"}},
+	"MNEG":  {"MNEG", "MNEG Rd, Rn, Rm", []string{"MUL Rd, Rn, Rm", "SUB Rd, XZR, Rd"}},
+	"SMULL": {"SMULL", "SMULL Rd, Rn, Rm", []string{"MUL Rd, Rn, Rm"}},
+	"UMULL": {"UMULL", "UMULL Rd, Rn, Rm", []string{"MUL Rd, Rn, Rm"}},
+}
+
+// Expand returns the instructions a pseudo-instruction is assembled as, given
+// the values of its operands in the order they appear in Syntax. Immediate
+// values are given without their leading #.
+func (p *Pseudo) Expand(operands []string) []string {
+	values := map[string]string{}
+	i := 0
+	MapOperands(p.Syntax, func(name string) string {
+		if i < len(operands) {
+			values[name] = operands[i]
+		}
+		i++
+		return name
+	})
+
+	expanded := make([]string, len(p.Expansion))
+	for i, instruction := range p.Expansion {
+		expanded[i] = MapOperands(instruction, func(name string) string {
+			if value, ok := values[name]; ok {
+				return value
+			}
+			return name
+		})
+	}
+	return expanded
+}
+
+// MapOperands rewrites each operand name in the syntax of an instruction,
+// such as Rd or ALU_immediate in "ADDI Rd, Rn, #ALU_immediate", leaving the
+// mnemonic, punctuation and the LSL keyword of a shift in place.
+func MapOperands(syntax string, f func(name string) string) string {
+	space := strings.IndexByte(syntax, ' ')
+	if space < 0 {
+		return syntax
+	}
+
+	var b strings.Builder
+	b.WriteString(syntax[:space+1])
+
+	operands := syntax[space+1:]
+	for i := 0; i < len(operands); {
+		end := i
+		for end < len(operands) && isIdentifierByte(operands[end]) {
+			end++
+		}
+		if end == i {
+			b.WriteByte(operands[i])
+			i++
+			continue
+		}
+		if word := operands[i:end]; word == "LSL" {
+			b.WriteString(word)
+		} else {
+			b.WriteString(f(word))
+		}
+		i = end
+	}
+	return b.String()
+}
+
+func isIdentifierByte(b byte) bool {
+	return (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '_'
+}
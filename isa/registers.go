@@ -0,0 +1,49 @@
+package isa
+
+import (
+	"strconv"
+)
+
+// Numbers of the integer registers with aliases.
+const (
+	SP  = 28
+	FP  = 29
+	LR  = 30
+	XZR = 31
+)
+
+// IntegerRegister returns the number of an integer register, accepting the
+// SP, FP, LR and XZR aliases.
+func IntegerRegister(name string) (int, bool) {
+	switch name {
+	case "SP":
+		return SP, true
+	case "FP":
+		return FP, true
+	case "LR":
+		return LR, true
+	case "XZR":
+		return XZR, true
+	}
+	if len(name) < 2 || name[0] != 'X' {
+		return 0, false
+	}
+	number, err := strconv.Atoi(name[1:])
+	if err != nil || number > 30 {
+		return 0, false
+	}
+	return number, true
+}
+
+// FloatRegister returns the number of a single (S0-S31) or double (D0-D31)
+// precision floating-point register.
+func FloatRegister(name string) (int, bool) {
+	if len(name) < 2 || (name[0] != 'S' && name[0] != 'D') {
+		return 0, false
+	}
+	number, err := strconv.Atoi(name[1:])
+	if err != nil || number > 31 {
+		return 0, false
+	}
+	return number, true
+}
@@ -0,0 +1,170 @@
+package languageserver
+
+import (
+	"fmt"
+
+	lsp "go.lsp.dev/protocol"
+
+	"server/parser"
+)
+
+// CodeActions returns quick fixes for the diagnostics the client reports in a
+// document, recognizing them by their codes.
+func CodeActions(doc *document, diagnostics []lsp.Diagnostic) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	for _, diagnostic := range diagnostics {
+		line := int(diagnostic.Range.Start.Line)
+		if line >= len(doc.lines) {
+			continue
+		}
+		tokens := *(*doc.tokens)[line]
+		if len(tokens) == 0 {
+			continue
+		}
+		start := int(diagnostic.Range.Start.Character)
+		code, _ := diagnostic.Code.(string)
+
+		switch code {
+		case RuleExpectedComma:
+			// the comma belongs straight after the operand before the diagnostic
+			previous, ok := tokenBefore(tokens, start)
+			if !ok {
+				continue
+			}
+			actions = append(actions, quickFix(doc, "Insert comma", diagnostic, true, insertAt(line, previous.End, ",")))
+		case RuleUnbalancedBrackets:
+			// only a missing bracket can be added. an unexpected one was found.
+			data, ok := dataOf(diagnostic)
+			if !ok || data.Expected != parser.RightBracketToken.String() || data.Found == parser.RightBracketToken.String() {
+				continue
+			}
+			last := tokens[len(tokens)-1]
+			actions = append(actions, quickFix(doc, "Add ']'", diagnostic, true, insertAt(line, last.End, "]")))
+		case RuleOperandType:
+			data, ok := dataOf(diagnostic)
+			if !ok {
+				continue
+			}
+			if number, ok := bareNumber(doc.lines[line], start); ok && data.Expected == parser.NumberToken.String() {
+				title := fmt.Sprintf("Insert '#' before %s", number)
+				actions = append(actions, quickFix(doc, title, diagnostic, true, insertAt(line, start, "#")))
+				continue
+			}
+			if data.Expected != parser.RegisterToken.String() {
+				continue
+			}
+			switch data.Found {
+			case parser.LabelToken.String():
+				token, ok := doc.tokenAt(diagnostic.Range.Start)
+				if !ok {
+					continue
+				}
+				actions = append(actions, replacements(doc, diagnostic, tokenRange(line, token), data.Suggestions)...)
+			case parser.NumberToken.String():
+				// the suggestion is the form of the instruction taking an immediate
+				actions = append(actions, replacements(doc, diagnostic, tokenRange(line, tokens[0]), data.Suggestions)...)
+			}
+		case RuleUnknownRegister:
+			token, ok := doc.tokenAt(diagnostic.Range.Start)
+			if !ok {
+				continue
+			}
+			actions = append(actions, replacements(doc, diagnostic, tokenRange(line, token), suggestRegisters(token.Value))...)
+		case RuleTrailingTokens:
+			// remove the tokens and the space before them, keeping any comment
+			previous, ok := tokenBefore(tokens, start)
+			if !ok {
+				continue
+			}
+			last := tokens[len(tokens)-1]
+			edit := lsp.TextEdit{Range: byteRange(line, previous.End, last.End)}
+			actions = append(actions, quickFix(doc, "Remove trailing tokens", diagnostic, false, edit))
+		case RuleExpectedInstruction:
+			if tokens[0].Type != parser.LabelToken {
+				continue
+			}
+			word, end := leadingWord(tokens)
+			actions = append(actions, replacements(doc, diagnostic, byteRange(line, tokens[0].Start, end), suggestMnemonics(word))...)
+		case RuleUndefinedLabel:
+			token, ok := doc.tokenAt(diagnostic.Range.Start)
+			if !ok || token.Type != parser.LabelToken {
+				continue
+			}
+			title := fmt.Sprintf("Create label '%s'", token.Value)
+			actions = append(actions, quickFix(doc, title, diagnostic, false, appendLine(doc, token.Value+":")))
+		}
+	}
+	return actions
+}
+
+// replacements returns a quick fix replacing r with each suggestion,
+// preferring the fix when there is only one.
+func replacements(doc *document, diagnostic lsp.Diagnostic, r lsp.Range, suggestions []string) []lsp.CodeAction {
+	actions := []lsp.CodeAction{}
+	for _, suggestion := range suggestions {
+		edit := lsp.TextEdit{Range: r, NewText: suggestion}
+		actions = append(actions, quickFix(doc, "Replace with "+suggestion, diagnostic, len(suggestions) == 1, edit))
+	}
+	return actions
+}
+
+func quickFix(doc *document, title string, diagnostic lsp.Diagnostic, preferred bool, edits ...lsp.TextEdit) lsp.CodeAction {
+	return lsp.CodeAction{
+		Title:       title,
+		Kind:        lsp.QuickFix,
+		Diagnostics: []lsp.Diagnostic{diagnostic},
+		IsPreferred: preferred,
+		Edit: &lsp.WorkspaceEdit{
+			Changes: map[lsp.DocumentURI][]lsp.TextEdit{
+				doc.uri: edits,
+			},
+		},
+	}
+}
+
+// tokenBefore returns the last token that ends at or before offset.
+func tokenBefore(tokens []*parser.Token, offset int) (*parser.Token, bool) {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].End <= offset {
+			return tokens[i], true
+		}
+	}
+	return nil, false
+}
+
+// bareNumber returns the number written without its '#' at offset in line.
+func bareNumber(line string, offset int) (string, bool) {
+	end := offset
+	if end < len(line) && line[end] == '-' {
+		end++
+	}
+	digits := end
+	for end < len(line) && line[end] >= '0' && line[end] <= '9' {
+		end++
+	}
+	if end == digits {
+		return "", false
+	}
+	return line[offset:end], true
+}
+
+func byteRange(line, start, end int) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: uint32(line), Character: uint32(start)},
+		End:   lsp.Position{Line: uint32(line), Character: uint32(end)},
+	}
+}
+
+func insertAt(line, offset int, text string) lsp.TextEdit {
+	return lsp.TextEdit{Range: byteRange(line, offset, offset), NewText: text}
+}
+
+// appendLine adds a line to the end of a document, keeping a final newline
+// last.
+func appendLine(doc *document, text string) lsp.TextEdit {
+	last := len(doc.lines) - 1
+	if doc.lines[last] == "" {
+		return insertAt(last, 0, text+"\n")
+	}
+	return insertAt(last, len(doc.lines[last]), "\n"+text)
+}
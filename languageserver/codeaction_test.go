@@ -0,0 +1,96 @@
+package languageserver
+
+import (
+	"encoding/json"
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestCodeActions(t *testing.T) {
+	tests := []struct {
+		input    string
+		title    string
+		expected string
+	}{
+		{"ADD X1 X2, X3", "Insert comma", "ADD X1, X2, X3"},
+		{"LDUR X1, [X2, #8 // load", "Add ']'", "LDUR X1, [X2, #8] // load"},
+		{"ADDI X1, X2, 5", "Insert '#' before 5", "ADDI X1, X2, #5"},
+		{"LDUR X1, [X2, 8]", "Insert '#' before 8", "LDUR X1, [X2, #8]"},
+		{"ADD X1, X2, X3 X4  // sum", "Remove trailing tokens", "ADD X1, X2, X3  // sum"},
+		{"  ADDD X1, X2, X3", "Replace with ADD", "  ADD X1, X2, X3"},
+		{"subi X1, X1, #1", "Replace with SUBI", "SUBI X1, X1, #1"},
+		{"B done", "Create label 'done'", "B done\ndone:"},
+		{"B done\n", "Create label 'done'", "B done\ndone:\n"},
+	}
+
+	for _, test := range tests {
+		doc := newDocument(uri.File("/tmp/test.legv8"), test.input)
+		actions := CodeActions(doc, *Analyze(doc.uri, doc.tokens, doc.Program()))
+		if len(actions) == 0 {
+			t.Errorf("Expected a code action, got none. Input: %q", test.input)
+			continue
+		}
+		action := actions[0]
+		if action.Title != test.title || action.Kind != lsp.QuickFix || len(action.Diagnostics) != 1 {
+			t.Errorf("Expected quick fix %q, got %q. Input: %q", test.title, action.Title, test.input)
+			continue
+		}
+
+		edits := action.Edit.Changes[doc.uri]
+		for i := len(edits) - 1; i >= 0; i-- {
+			doc.applyChange(edits[i].Range, edits[i].NewText)
+		}
+		if doc.Text() != test.expected {
+			t.Errorf("Expected %q after the fix, got %q. Input: %q", test.expected, doc.Text(), test.input)
+		}
+		for _, d := range *Analyze(doc.uri, doc.tokens, doc.Program()) {
+			if d.Code == action.Diagnostics[0].Code && d.Severity == lsp.DiagnosticSeverityError {
+				t.Errorf("Expected the fix to resolve %s, got %q. Input: %q", d.Code, d.Message, test.input)
+			}
+		}
+	}
+}
+
+func TestCodeActionsWithoutFix(t *testing.T) {
+	inputs := []string{
+		"ZZZZZ X1, X2, X3",
+		"ADD X1, #2, X3",
+		"ADDI X1, X2, loop",
+		"LDUR X1, X2, #8]",
+		"ADDI X1, X2], #8",
+	}
+
+	for _, input := range inputs {
+		doc := newDocument(uri.File("/tmp/test.legv8"), input)
+		if actions := CodeActions(doc, *Analyze(doc.uri, doc.tokens, doc.Program())); len(actions) != 0 {
+			t.Errorf("Expected no code actions, got %v. Input: %q", actions, input)
+		}
+	}
+}
+
+func TestCodeActionsFromClient(t *testing.T) {
+	inputs := []string{
+		"LDUR X1, [X2, #8",
+		"ADDI X1, X2, 5",
+		"ADD X1, X2, #3",
+		"ADD X1, X2, x3",
+	}
+
+	for _, input := range inputs {
+		doc := newDocument(uri.File("/tmp/test.legv8"), input)
+		// the client sends diagnostics back with their data decoded from JSON
+		raw, err := json.Marshal(*Analyze(doc.uri, doc.tokens, doc.Program()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		diagnostics := []lsp.Diagnostic{}
+		if err := json.Unmarshal(raw, &diagnostics); err != nil {
+			t.Fatal(err)
+		}
+		if actions := CodeActions(doc, diagnostics); len(actions) == 0 {
+			t.Errorf("Expected a code action, got none. Input: %q", input)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package languageserver
+
+import (
+	"fmt"
+	"sort"
+
+	lsp "go.lsp.dev/protocol"
+
+	"server/isa"
+	"server/parser"
+)
+
+// Completion returns the completion items available at a position in a
+// document, based on the operand expected at the cursor.
+func Completion(doc *document, position lsp.Position, snippets bool) *lsp.CompletionList {
+	list := &lsp.CompletionList{Items: []lsp.CompletionItem{}}
+
+	line := int(position.Line)
+	if line >= len(doc.lines) {
+		return list
+	}
+	offset := utf16ToByteOffset(doc.lines[line], int(position.Character))
+	tokens := []*parser.Token{}
+	for _, token := range *(*doc.tokens)[line] {
+		if token.Start < offset {
+			tokens = append(tokens, token)
+		}
+	}
+
+	// the operand slot the cursor is in. a token touching the cursor is still
+	// being typed, so it occupies the slot being completed.
+	slot := len(tokens)
+	if slot > 0 {
+		last := tokens[slot-1]
+		if last.End >= offset && !parser.IsPunctuation(last.Type) {
+			slot--
+		}
+	}
+
+	if slot == 0 {
+		list.Items = mnemonicCompletions(snippets)
+		return list
+	}
+
+	if tokens[0].Type != parser.InstructionToken {
+		return list
+	}
+	exp := *parser.Expected(tokens[0])
+	if slot >= len(exp) {
+		return list
+	}
+
+	switch exp[slot] {
+	case parser.RegisterToken:
+		n := 0
+		for _, t := range exp[:slot] {
+			if t == parser.RegisterToken {
+				n++
+			}
+		}
+
+		switch {
+		case expectedRegisterClass(tokens[0].Value, n) == SingleRegister:
+			list.Items = registerCompletions(singleRegisters)
+		case expectedRegisterClass(tokens[0].Value, n) == DoubleRegister:
+			list.Items = registerCompletions(doubleRegisters)
+		case tokens[slot-1].Type == parser.LeftBracketToken:
+			// the register after a left bracket is the base address of a memory access
+			list.Items = registerCompletions(baseRegisters)
+		default:
+			list.Items = registerCompletions(registers)
+		}
+	case parser.LabelToken:
+		list.Items = labelCompletions(doc)
+	}
+
+	return list
+}
+
+// registers are the names of every integer register, including aliases.
+var registers []string
+
+// baseRegisters are the registers that may hold the base address of a memory access.
+var baseRegisters []string
+
+// singleRegisters and doubleRegisters are the names of the floating-point registers.
+var singleRegisters, doubleRegisters []string
+
+func mnemonicCompletions(snippets bool) []lsp.CompletionItem {
+	mnemonics := make([]string, 0, len(parser.KeywordInstructionTypes))
+	for mnemonic := range parser.KeywordInstructionTypes {
+		mnemonics = append(mnemonics, mnemonic)
+	}
+	sort.Strings(mnemonics)
+
+	items := make([]lsp.CompletionItem, 0, len(mnemonics))
+	for _, mnemonic := range mnemonics {
+		info := Instructions[mnemonic]
+		item := lsp.CompletionItem{
+			Label:  mnemonic,
+			Kind:   lsp.CompletionItemKindKeyword,
+			Detail: fmt.Sprintf("%s: %s", formatName(parser.KeywordInstructionTypes[mnemonic]), info.Description),
+			Documentation: lsp.MarkupContent{
+				Kind:  lsp.Markdown,
+				Value: info.Markdown(),
+			},
+		}
+		if snippets {
+			item.InsertText = snippet(info.Syntax)
+			item.InsertTextFormat = lsp.InsertTextFormatSnippet
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func registerCompletions(names []string) []lsp.CompletionItem {
+	items := make([]lsp.CompletionItem, 0, len(names))
+	for i, name := range names {
+		detail := registerClass(name).String()
+		if number, ok := isa.IntegerRegister(name); ok {
+			detail = fmt.Sprintf("X%d", number)
+		}
+		items = append(items, lsp.CompletionItem{
+			Label:    name,
+			Kind:     lsp.CompletionItemKindVariable,
+			Detail:   detail,
+			SortText: fmt.Sprintf("%02d", i),
+		})
+	}
+	return items
+}
+
+func labelCompletions(doc *document) []lsp.CompletionItem {
+	items := []lsp.CompletionItem{}
+	seen := map[string]bool{}
+	for _, label := range doc.Program().Labels() {
+		if seen[label.Name] {
+			continue
+		}
+		seen[label.Name] = true
+		items = append(items, lsp.CompletionItem{
+			Label:  label.Name,
+			Kind:   lsp.CompletionItemKindReference,
+			Detail: fmt.Sprintf("Label on line %d", label.Range.Start.Line+1),
+		})
+	}
+	return items
+}
+
+// snippet converts the syntax of an instruction, such as "ADD Rd, Rn, Rm",
+// into a snippet with a placeholder for each operand.
+func snippet(syntax string) string {
+	placeholder := 0
+	return isa.MapOperands(syntax, func(name string) string {
+		placeholder++
+		return fmt.Sprintf("${%d:%s}", placeholder, name)
+	})
+}
+
+func init() {
+	for i := 0; i <= 30; i++ {
+		registers = append(registers, fmt.Sprintf("X%d", i))
+	}
+	baseRegisters = append(append([]string{}, registers...), "SP", "FP")
+	registers = append(registers, "XZR", "SP", "FP", "LR")
+
+	for i := 0; i <= 31; i++ {
+		singleRegisters = append(singleRegisters, fmt.Sprintf("S%d", i))
+		doubleRegisters = append(doubleRegisters, fmt.Sprintf("D%d", i))
+	}
+}
@@ -0,0 +1,65 @@
+package languageserver
+
+import (
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestCompletion(t *testing.T) {
+	inputs := []struct {
+		line     string
+		contains string
+		excludes string
+	}{
+		{"", "ADDI", "X0"},
+		{"AD", "ADD", "X0"},
+		{"ADD X1, ", "X30", "ADD"},
+		{"ADD X1, X", "XZR", "loop"},
+		{"LDUR X1, [", "SP", "XZR"},
+		{"B ", "loop", "X0"},
+		{"CBZ X1, ", "done", "X1"},
+		{"B.EQ d", "done", "ADD"},
+		{"FADDS S0, ", "S31", "X0"},
+		{"FMULD D0, D1, ", "D2", "S2"},
+		{"LDURS S0, [", "SP", "S1"},
+	}
+
+	for _, in := range inputs {
+		doc := newDocument(uri.File("/tmp/test.legv8"), "loop:\ndone:\n"+in.line)
+		list := Completion(doc, lsp.Position{Line: 2, Character: uint32(len(in.line))}, false)
+
+		labels := map[string]bool{}
+		for _, item := range list.Items {
+			labels[item.Label] = true
+		}
+		if !labels[in.contains] {
+			t.Errorf("Expected completion %s. Input: %q", in.contains, in.line)
+		}
+		if labels[in.excludes] {
+			t.Errorf("Unexpected completion %s. Input: %q", in.excludes, in.line)
+		}
+	}
+
+	if list := Completion(newDocument(uri.File("/tmp/test.legv8"), "ADDI X0, X1, "), lsp.Position{Line: 0, Character: 13}, false); len(list.Items) != 0 {
+		t.Errorf("Expected no completions for an immediate operand. Received %v", list.Items)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	inputs := map[string]string{
+		"ADD Rd, Rn, Rm":                      "ADD ${1:Rd}, ${2:Rn}, ${3:Rm}",
+		"LDUR Rt, [Rn, #DT_address]":          "LDUR ${1:Rt}, [${2:Rn}, #${3:DT_address}]",
+		"B.EQ label":                          "B.EQ ${1:label}",
+		"HALT":                                "HALT",
+		"MOVZ Rd, #MOV_immediate, LSL #shift": "MOVZ ${1:Rd}, #${2:MOV_immediate}, LSL #${3:shift}",
+		"MOVK Rd, #MOV_immediate, LSL #shift": "MOVK ${1:Rd}, #${2:MOV_immediate}, LSL #${3:shift}",
+	}
+
+	for in, expect := range inputs {
+		if out := snippet(in); out != expect {
+			t.Errorf("Expected snippet %q, got %q.", expect, out)
+		}
+	}
+}
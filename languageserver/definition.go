@@ -0,0 +1,51 @@
+package languageserver
+
+import (
+	lsp "go.lsp.dev/protocol"
+
+	"server/parser"
+)
+
+// Definition returns the location of the label definition for the label under
+// a position in a document.
+func Definition(doc *document, position lsp.Position) []lsp.Location {
+	token, ok := doc.tokenAt(position)
+	if !ok || token.Type != parser.LabelToken {
+		return nil
+	}
+
+	definition, ok := doc.Program().Label(token.Value)
+	if !ok {
+		return nil
+	}
+
+	return []lsp.Location{{URI: doc.uri, Range: lspRange(definition.Range)}}
+}
+
+// References returns the location of every branch to the label under a
+// position in a document, optionally including the label definition itself.
+func References(doc *document, position lsp.Position, includeDeclaration bool) []lsp.Location {
+	token, ok := doc.tokenAt(position)
+	if !ok || token.Type != parser.LabelToken {
+		return nil
+	}
+
+	locations := []lsp.Location{}
+	if includeDeclaration {
+		if definition, ok := doc.Program().Label(token.Value); ok {
+			locations = append(locations, lsp.Location{URI: doc.uri, Range: lspRange(definition.Range)})
+		}
+	}
+	for _, reference := range doc.Program().LabelRefs(token.Value) {
+		locations = append(locations, lsp.Location{URI: doc.uri, Range: lspRange(reference.Range)})
+	}
+
+	return locations
+}
+
+func tokenRange(line int, token *parser.Token) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: uint32(line), Character: uint32(token.Start)},
+		End:   lsp.Position{Line: uint32(line), Character: uint32(token.End)},
+	}
+}
@@ -0,0 +1,43 @@
+package languageserver
+
+import (
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestDefinition(t *testing.T) {
+	doc := newDocument(uri.File("/tmp/test.legv8"), "B loop\nloop:\nCBZ X1, loop\nB done")
+
+	locations := Definition(doc, lsp.Position{Line: 0, Character: 3})
+	if len(locations) != 1 {
+		t.Fatalf("Expected 1 definition, got %d.", len(locations))
+	}
+	if locations[0].Range.Start.Line != 1 || locations[0].Range.Start.Character != 0 || locations[0].Range.End.Character != 4 {
+		t.Errorf("Incorrect definition range %v.", locations[0].Range)
+	}
+
+	if locations := Definition(doc, lsp.Position{Line: 3, Character: 3}); len(locations) != 0 {
+		t.Errorf("Expected no definition for undefined label, got %v.", locations)
+	}
+	if locations := Definition(doc, lsp.Position{Line: 2, Character: 4}); len(locations) != 0 {
+		t.Errorf("Expected no definition for register, got %v.", locations)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	doc := newDocument(uri.File("/tmp/test.legv8"), "B loop\nloop:\nCBZ X1, loop\nB done")
+
+	locations := References(doc, lsp.Position{Line: 1, Character: 1}, false)
+	if len(locations) != 2 {
+		t.Fatalf("Expected 2 references, got %d.", len(locations))
+	}
+	if locations[0].Range.Start.Line != 0 || locations[1].Range.Start.Line != 2 || locations[1].Range.Start.Character != 8 {
+		t.Errorf("Incorrect references %v.", locations)
+	}
+
+	if locations := References(doc, lsp.Position{Line: 0, Character: 2}, true); len(locations) != 3 {
+		t.Errorf("Expected 3 references including declaration, got %d.", len(locations))
+	}
+}
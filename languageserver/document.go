@@ -0,0 +1,215 @@
+package languageserver
+
+import (
+	"strings"
+	"sync"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+
+	"server/ast"
+	"server/parser"
+)
+
+// contentChange is a change reported by textDocument/didChange. Unlike
+// lsp.TextDocumentContentChangeEvent, a missing range is distinguishable from
+// an empty range at the start of the document.
+type contentChange struct {
+	Range *lsp.Range `json:"range,omitempty"`
+	Text  string     `json:"text"`
+}
+
+// document is the live contents of a text document opened by the client.
+type document struct {
+	uri    uri.URI
+	lines  []string
+	tokens *[]*[]*parser.Token
+
+	// program is built from tokens once the store has applied a change, so
+	// every feature shares the same tree for a version of the document.
+	program *ast.Program
+
+	// semantic is the last semantic tokens result sent for the document.
+	semantic *semanticTokensResult
+}
+
+func newDocument(u uri.URI, text string) *document {
+	d := &document{uri: u}
+	d.setText(text)
+	return d
+}
+
+// setText replaces the contents of the document and re-tokenizes every line.
+func (d *document) setText(text string) {
+	d.lines = parser.SplitLines(text)
+	tokens := make([]*[]*parser.Token, len(d.lines))
+	for i, line := range d.lines {
+		tokens[i] = parser.TokenizeLine(line)
+	}
+	d.tokens = &tokens
+	d.program = nil
+}
+
+// applyChange replaces the text within r with text, re-tokenizing only the
+// lines touched by the change.
+func (d *document) applyChange(r lsp.Range, text string) {
+	startLine, startChar := d.offset(r.Start)
+	endLine, endChar := d.offset(r.End)
+	if endLine < startLine || (endLine == startLine && endChar < startChar) {
+		startLine, startChar, endLine, endChar = endLine, endChar, startLine, startChar
+	}
+
+	prefix := d.lines[startLine][:startChar]
+	suffix := d.lines[endLine][endChar:]
+	replacement := parser.SplitLines(prefix + text + suffix)
+
+	replacementTokens := make([]*[]*parser.Token, len(replacement))
+	for i, line := range replacement {
+		replacementTokens[i] = parser.TokenizeLine(line)
+	}
+
+	tokens := *d.tokens
+	d.lines = append(d.lines[:startLine], append(replacement, d.lines[endLine+1:]...)...)
+	tokens = append(tokens[:startLine], append(replacementTokens, tokens[endLine+1:]...)...)
+	d.tokens = &tokens
+	d.program = nil
+}
+
+// Program returns the syntax tree of the document, building it if the
+// document has changed since it was last built.
+func (d *document) Program() *ast.Program {
+	if d.program == nil {
+		d.program = parser.BuildProgram(d.tokens)
+	}
+	return d.program
+}
+
+// offset converts an LSP position into a line index and byte offset within
+// that line, clamping positions that fall outside the document.
+func (d *document) offset(p lsp.Position) (int, int) {
+	line := int(p.Line)
+	if line >= len(d.lines) {
+		line = len(d.lines) - 1
+		return line, len(d.lines[line])
+	}
+	return line, utf16ToByteOffset(d.lines[line], int(p.Character))
+}
+
+// Text returns the full contents of the document.
+func (d *document) Text() string {
+	return strings.Join(d.lines, "\n")
+}
+
+// documentStore holds every document the client currently has open, keyed by URI.
+type documentStore struct {
+	mu        sync.RWMutex
+	documents map[uri.URI]*document
+}
+
+func newDocumentStore() *documentStore {
+	return &documentStore{
+		documents: map[uri.URI]*document{},
+	}
+}
+
+// open begins tracking a document with the given contents.
+func (s *documentStore) open(u uri.URI, text string) *document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := newDocument(u, text)
+	d.Program()
+	s.documents[u] = d
+	return d
+}
+
+// update applies a sequence of changes to an open document. Changes without
+// a range replace the full contents of the document.
+func (s *documentStore) update(u uri.URI, changes []contentChange) *document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.documents[u]
+	if !ok {
+		d = newDocument(u, "")
+		s.documents[u] = d
+	}
+	for _, change := range changes {
+		if change.Range == nil {
+			d.setText(change.Text)
+			continue
+		}
+		d.applyChange(*change.Range, change.Text)
+	}
+	d.Program()
+	return d
+}
+
+// close stops tracking a document.
+func (s *documentStore) close(u uri.URI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.documents, u)
+}
+
+// get returns the open document for a URI, if any.
+func (s *documentStore) get(u uri.URI) (*document, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.documents[u]
+	return d, ok
+}
+
+// uris returns the URIs of every open document.
+func (s *documentStore) uris() []uri.URI {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	uris := make([]uri.URI, 0, len(s.documents))
+	for u := range s.documents {
+		uris = append(uris, u)
+	}
+	return uris
+}
+
+// lspRange converts a syntax tree range into an LSP range.
+func lspRange(r ast.Range) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: uint32(r.Start.Line), Character: uint32(r.Start.Column)},
+		End:   lsp.Position{Line: uint32(r.End.Line), Character: uint32(r.End.Column)},
+	}
+}
+
+// utf16ToByteOffset converts a character offset counted in UTF-16 code units,
+// as used by LSP positions, into a byte offset within line.
+func utf16ToByteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+	}
+	return len(line)
+}
+
+// tokenAt returns the token under a position, if any.
+func (d *document) tokenAt(p lsp.Position) (*parser.Token, bool) {
+	line := int(p.Line)
+	if line >= len(d.lines) {
+		return nil, false
+	}
+	offset := utf16ToByteOffset(d.lines[line], int(p.Character))
+	for _, token := range *(*d.tokens)[line] {
+		if token.Start <= offset && offset < token.End {
+			return token, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,86 @@
+package languageserver
+
+import (
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+
+	"server/parser"
+)
+
+func TestDocumentStore(t *testing.T) {
+	store := newDocumentStore()
+	u := uri.File("/tmp/test.legv8")
+
+	store.open(u, "ADDI X0, X1, #12\r\nloop:\nB loop")
+	doc, ok := store.get(u)
+	if !ok {
+		t.Fatalf("Document not found after open.")
+	}
+	if len(doc.lines) != 3 {
+		t.Errorf("Expected 3 lines, got %d.", len(doc.lines))
+	}
+	if doc.lines[0] != "ADDI X0, X1, #12" {
+		t.Errorf("Expected carriage return to be stripped, got %q.", doc.lines[0])
+	}
+	if len(*doc.tokens) != 3 || len(*(*doc.tokens)[0]) != 6 {
+		t.Errorf("Document was not tokenized. Tokens=%v", *doc.tokens)
+	}
+
+	program := doc.program
+	if program == nil || doc.Program() != program || len(program.Labels()) != 1 {
+		t.Errorf("Expected the program to be built once on open, got %v.", program)
+	}
+
+	store.update(u, []contentChange{{Text: "HALT"}})
+	doc, _ = store.get(u)
+	if doc.program == nil || doc.program == program || len(doc.program.Labels()) != 0 {
+		t.Errorf("Expected the program to be rebuilt after a change, got %v.", doc.program)
+	}
+	if doc.Text() != "HALT" {
+		t.Errorf("Expected replaced text 'HALT', got %q.", doc.Text())
+	}
+	if len(*doc.tokens) != 1 || (*(*doc.tokens)[0])[0].InstructionType != parser.IGNORE {
+		t.Errorf("Document was not re-tokenized after full change. Tokens=%v", *doc.tokens)
+	}
+
+	store.close(u)
+	if _, ok := store.get(u); ok {
+		t.Errorf("Document still present after close.")
+	}
+}
+
+func TestDocumentIncrementalChange(t *testing.T) {
+	inputs := []struct {
+		text     string
+		change   lsp.Range
+		newText  string
+		expected string
+	}{
+		{"ADDI X0 X1, #12", lsp.Range{Start: lsp.Position{Line: 0, Character: 7}, End: lsp.Position{Line: 0, Character: 7}}, ",", "ADDI X0, X1, #12"},
+		{"loop:\nB loop\nHALT", lsp.Range{Start: lsp.Position{Line: 0, Character: 5}, End: lsp.Position{Line: 1, Character: 6}}, "", "loop:\nHALT"},
+		{"HALT", lsp.Range{Start: lsp.Position{Line: 0, Character: 0}, End: lsp.Position{Line: 0, Character: 0}}, "top:\n", "top:\nHALT"},
+		{"// \U0001F600 x\nHALT", lsp.Range{Start: lsp.Position{Line: 0, Character: 6}, End: lsp.Position{Line: 0, Character: 7}}, "y", "// \U0001F600 y\nHALT"},
+		{"HALT", lsp.Range{Start: lsp.Position{Line: 5, Character: 0}, End: lsp.Position{Line: 5, Character: 0}}, "\nDUMP", "HALT\nDUMP"},
+	}
+
+	for _, in := range inputs {
+		doc := newDocument(uri.File("/tmp/test.legv8"), in.text)
+		doc.applyChange(in.change, in.newText)
+
+		if doc.Text() != in.expected {
+			t.Errorf("Expected text %q, got %q. Input: %q", in.expected, doc.Text(), in.text)
+			continue
+		}
+		if len(*doc.tokens) != len(doc.lines) {
+			t.Errorf("Expected %d tokenized lines, got %d. Input: %q", len(doc.lines), len(*doc.tokens), in.text)
+			continue
+		}
+		for i, line := range doc.lines {
+			if len(*parser.TokenizeLine(line)) != len(*(*doc.tokens)[i]) {
+				t.Errorf("Line %d was not re-tokenized. Input: %q", i, in.text)
+			}
+		}
+	}
+}
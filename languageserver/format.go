@@ -0,0 +1,286 @@
+package languageserver
+
+import (
+	"strings"
+
+	lsp "go.lsp.dev/protocol"
+
+	"server/parser"
+)
+
+// DefaultTabWidth is the indentation of instructions when none is configured.
+const DefaultTabWidth = 4
+
+// FormatOptions configures the layout produced by Format.
+type FormatOptions struct {
+	// TabWidth is the width of one level of indentation.
+	TabWidth int
+
+	// UseTabs indents with a tab rather than TabWidth spaces.
+	UseTabs bool
+}
+
+// formatOptions converts the options of an LSP formatting request.
+func formatOptions(options lsp.FormattingOptions) FormatOptions {
+	width := int(options.TabSize)
+	if width <= 0 {
+		width = DefaultTabWidth
+	}
+	return FormatOptions{TabWidth: width, UseTabs: !options.InsertSpaces}
+}
+
+func (o FormatOptions) indent() string {
+	if o.UseTabs {
+		return "\t"
+	}
+	return strings.Repeat(" ", o.TabWidth)
+}
+
+// width returns the number of columns text occupies, counting tabs as a full
+// level of indentation.
+func (o FormatOptions) width(text string) int {
+	width := 0
+	for _, r := range text {
+		if r == '\t' {
+			width += o.TabWidth
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// Format lays out LEGv8 source: labels start in column 0, instructions are
+// indented with their mnemonics in upper case and their operands aligned in a
+// column, and trailing comments on consecutive lines are aligned. Lines the
+// tokenizer cannot make sense of are left as they are, apart from trailing
+// whitespace. Formatting formatted text leaves it unchanged, and the text
+// keeps its line endings.
+func Format(text string, options FormatOptions) string {
+	return strings.Join(formatLines(parser.SplitLines(text), options), lineEnding(text))
+}
+
+// lineEnding returns the line ending text uses, judged by its first line.
+func lineEnding(text string) string {
+	if end := strings.IndexByte(text, '\n'); end > 0 && text[end-1] == '\r' {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// layout is a line split into the parts the formatter aligns.
+type layout struct {
+	code    string
+	comment string
+
+	// mnemonic and operands are set for instruction lines, whose code is built
+	// once the width of the mnemonic column is known.
+	mnemonic string
+	operands string
+
+	// fixed lines are kept as they are and not aligned with their neighbours.
+	fixed bool
+}
+
+func formatLines(lines []string, options FormatOptions) []string {
+	layouts := make([]layout, len(lines))
+	mnemonicWidth := 0
+	for i, line := range lines {
+		layouts[i] = layoutLine(line, options)
+		if layouts[i].operands != "" && len(layouts[i].mnemonic) > mnemonicWidth {
+			mnemonicWidth = len(layouts[i].mnemonic)
+		}
+	}
+
+	for i := range layouts {
+		l := &layouts[i]
+		if l.mnemonic == "" {
+			continue
+		}
+		l.code = options.indent() + l.mnemonic
+		if l.operands != "" {
+			l.code += strings.Repeat(" ", mnemonicWidth-len(l.mnemonic)+1) + l.operands
+		}
+	}
+
+	formatted := make([]string, len(lines))
+	for i := 0; i < len(layouts); {
+		if !hasTrailingComment(layouts[i]) {
+			formatted[i] = layouts[i].code + layouts[i].comment
+			i++
+			continue
+		}
+
+		// align the comments of a run of lines that all have trailing comments
+		end := i
+		column := 0
+		for ; end < len(layouts) && hasTrailingComment(layouts[end]); end++ {
+			if width := options.width(layouts[end].code) + 1; width > column {
+				column = width
+			}
+		}
+		for ; i < end; i++ {
+			padding := column - options.width(layouts[i].code)
+			formatted[i] = layouts[i].code + strings.Repeat(" ", padding) + layouts[i].comment
+		}
+	}
+	return formatted
+}
+
+func hasTrailingComment(l layout) bool {
+	return !l.fixed && l.code != "" && l.comment != ""
+}
+
+// layoutLine splits a line into its code and comment, normalizing the code
+// of label definitions and instructions.
+func layoutLine(line string, options FormatOptions) layout {
+	line = normalizeMnemonic(strings.TrimRight(line, " \t"))
+	tokens := parser.TokenizeLine(line)
+
+	l := layout{}
+	code := line
+	if start, ok := commentStart(line, tokens); ok {
+		code, l.comment = line[:start], line[start:]
+	}
+
+	if len(*tokens) == 0 {
+		// a comment on a line of its own keeps to the margin or to the
+		// instructions, whichever it was closer to
+		if code != "" {
+			l.comment = options.indent() + l.comment
+		}
+		return l
+	}
+	for _, token := range *tokens {
+		if token.Type == parser.UnknownToken {
+			return layout{code: line, fixed: true}
+		}
+	}
+
+	first := (*tokens)[0]
+	switch {
+	case len(*tokens) == 2 && (*tokens)[1].Type == parser.ColonToken && (first.Type == parser.LabelToken || first.Type == parser.InstructionToken):
+		l.code = first.Value + ":"
+	case first.Type == parser.InstructionToken && (len(*tokens) == 1 || (*tokens)[1].Type != parser.ColonToken):
+		l.mnemonic = first.Value
+		expected := []parser.TokenType{}
+		if exp := parser.Expected(first); exp != nil {
+			expected = (*exp)[1:]
+		}
+		l.operands = joinOperands(line, (*tokens)[1:], expected)
+	default:
+		return layout{code: line, fixed: true}
+	}
+	return l
+}
+
+// joinOperands writes operands with a space after each comma and none inside
+// brackets, given the tokens expected in their place.
+func joinOperands(line string, tokens []*parser.Token, expected []parser.TokenType) string {
+	var b strings.Builder
+	for i, token := range tokens {
+		if i > 0 {
+			previous := tokens[i-1].Type
+			if previous != parser.LeftBracketToken && token.Type != parser.CommaToken && token.Type != parser.RightBracketToken {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(operandText(line, token, i, expected))
+	}
+	return b.String()
+}
+
+// operandText returns the text of the i-th operand token. The tokenizer reads
+// registers in lower case as labels, so a label naming a register where a
+// register is expected is written in upper case. Branch targets keep their
+// case, since labels are case-sensitive.
+func operandText(line string, token *parser.Token, i int, expected []parser.TokenType) string {
+	text := line[token.Start:token.End]
+	upper := strings.ToUpper(text)
+	if token.Type == parser.LabelToken && i < len(expected) && expected[i] == parser.RegisterToken && isRegisterName(upper) {
+		return upper
+	}
+	return text
+}
+
+// normalizeMnemonic upper-cases the first word of a line if that makes it a
+// mnemonic, since the tokenizer only recognizes mnemonics in upper case.
+func normalizeMnemonic(line string) string {
+	start := len(line) - len(strings.TrimLeft(line, " \t"))
+	end := strings.IndexAny(line[start:], " \t")
+	if end < 0 {
+		end = len(line)
+	} else {
+		end += start
+	}
+
+	word := line[start:end]
+	upper := strings.ToUpper(word)
+	if _, ok := parser.KeywordInstructionTypes[upper]; !ok || upper == word {
+		return line
+	}
+	// a label may share its name with a mnemonic in another case
+	if rest := strings.TrimLeft(line[end:], " \t"); strings.HasPrefix(rest, ":") {
+		return line
+	}
+	return line[:start] + upper + line[end:]
+}
+
+// Formatting returns the edits that format a whole document.
+func Formatting(doc *document, options FormatOptions) []lsp.TextEdit {
+	return formatEdits(doc, options, 0, len(doc.lines)-1)
+}
+
+// RangeFormatting returns the edits that format the lines a range touches.
+// Columns are still aligned across the whole document.
+func RangeFormatting(doc *document, r lsp.Range, options FormatOptions) []lsp.TextEdit {
+	first, last := int(r.Start.Line), int(r.End.Line)
+	// a selection of whole lines ends at the start of the next line
+	if last > first && r.End.Character == 0 {
+		last--
+	}
+	return formatEdits(doc, options, first, last)
+}
+
+// OnTypeFormatting returns the edits that format the line just finished
+// when a newline is typed.
+func OnTypeFormatting(doc *document, position lsp.Position, ch string, options FormatOptions) []lsp.TextEdit {
+	if ch != "\n" || position.Line == 0 {
+		return []lsp.TextEdit{}
+	}
+	line := int(position.Line) - 1
+	return formatEdits(doc, options, line, line)
+}
+
+// formatEdits returns an edit for each line from first to last, inclusive,
+// that formatting changes.
+func formatEdits(doc *document, options FormatOptions, first, last int) []lsp.TextEdit {
+	formatted := formatLines(doc.lines, options)
+	edits := []lsp.TextEdit{}
+	for i := first; i <= last && i < len(doc.lines); i++ {
+		if formatted[i] == doc.lines[i] {
+			continue
+		}
+		edits = append(edits, lsp.TextEdit{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: uint32(i)},
+				End:   lsp.Position{Line: uint32(i), Character: uint32(utf16Length(doc.lines[i]))},
+			},
+			NewText: formatted[i],
+		})
+	}
+	return edits
+}
+
+// utf16Length returns the length of text in UTF-16 code units.
+func utf16Length(text string) int {
+	length := 0
+	for _, r := range text {
+		if r >= 0x10000 {
+			length += 2
+		} else {
+			length++
+		}
+	}
+	return length
+}
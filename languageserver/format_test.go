@@ -0,0 +1,101 @@
+package languageserver
+
+import (
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestFormat(t *testing.T) {
+	options := FormatOptions{TabWidth: 4}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ADD X1,X2 ,X3", "    ADD X1, X2, X3"},
+		{"  add X1, X2, X3", "    ADD X1, X2, X3"},
+		{"b.eq loop", "    B.EQ loop"},
+		{"   loop:   ", "loop:"},
+		{"add:", "add:"},
+		{"LDUR X1,[ X2 ,#8 ]", "    LDUR X1, [X2, #8]"},
+		{"MOVZ X1,#5,LSL #16", "    MOVZ X1, #5, LSL #16"},
+		{"HALT  ", "    HALT"},
+		{"ADD X1, X2, X3\nLDURSW X4, [X5, #0]\nB done", "    ADD    X1, X2, X3\n    LDURSW X4, [X5, #0]\n    B      done"},
+		{"ADD X1, X2, X3 // sum\nSUBI X4, X4, #1   // count\n\nB loop // again", "    ADD  X1, X2, X3 // sum\n    SUBI X4, X4, #1 // count\n\n    B    loop // again"},
+		{"// header\n  // body", "// header\n    // body"},
+		{"ADD X1, X2, @  // unknown  ", "ADD X1, X2, @  // unknown"},
+		{"X1 X2", "X1 X2"},
+		{"ADD X1, X2, X3\n", "    ADD X1, X2, X3\n"},
+		{"add x1, x2, x3", "    ADD X1, X2, X3"},
+		{"ldur x1, [sp, #8]\nadd fp, lr, xzr", "    LDUR X1, [SP, #8]\n    ADD  FP, LR, XZR"},
+		{"fadds s1, s2, s3", "    FADDS S1, S2, S3"},
+		{"cbz x1, x2\nb x1", "    CBZ X1, x2\n    B   x1"},
+		{"loop:\r\nadd X1,X2,X3 // sum\r\n", "loop:\r\n    ADD X1, X2, X3 // sum\r\n"},
+		{"    HALT\r\n\r\n    HALT", "    HALT\r\n\r\n    HALT"},
+	}
+
+	for _, test := range tests {
+		formatted := Format(test.input, options)
+		if formatted != test.expected {
+			t.Errorf("Expected %q, got %q. Input: %q", test.expected, formatted, test.input)
+		}
+		if again := Format(formatted, options); again != formatted {
+			t.Errorf("Expected formatting to be idempotent, got %q. Input: %q", again, formatted)
+		}
+	}
+}
+
+func TestFormatTabs(t *testing.T) {
+	options := FormatOptions{TabWidth: 8, UseTabs: true}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ADD X1, X2, X3 // a\nloop: // b", "\tADD X1, X2, X3 // a\nloop:                  // b"},
+		{"main: // a\n\tadd\tX1,\tX2, X3\n\tB main", "main: // a\n\tADD X1, X2, X3\n\tB   main"},
+	}
+
+	for _, test := range tests {
+		formatted := Format(test.input, options)
+		if formatted != test.expected {
+			t.Errorf("Expected %q, got %q. Input: %q", test.expected, formatted, test.input)
+		}
+		if again := Format(formatted, options); again != formatted {
+			t.Errorf("Expected formatting to be idempotent, got %q. Input: %q", again, formatted)
+		}
+		doc := newDocument(uri.File("/tmp/test.legv8"), formatted)
+		for _, d := range *Analyze(doc.uri, doc.tokens, doc.Program()) {
+			if d.Severity == lsp.DiagnosticSeverityError {
+				t.Errorf("Expected formatted output to have no errors, got %q. Input: %q", d.Message, test.input)
+			}
+		}
+	}
+}
+
+func TestFormattingEdits(t *testing.T) {
+	doc := newDocument(uri.File("/tmp/test.legv8"), "loop:\n    ADD X1, X2, X3\nsubi X1,X1,#1\n  CBZ X1, loop")
+	options := formatOptions(lsp.FormattingOptions{TabSize: 4, InsertSpaces: true})
+
+	edits := Formatting(doc, options)
+	if len(edits) != 3 || edits[0].Range.Start.Line != 1 || edits[0].NewText != "    ADD  X1, X2, X3" || edits[2].NewText != "    CBZ  X1, loop" {
+		t.Fatalf("Incorrect formatting edits %v.", edits)
+	}
+	if edits[1].Range.End.Character != 13 || edits[2].Range.End.Character != 14 {
+		t.Errorf("Expected edits to replace whole lines, got %v.", edits)
+	}
+
+	// operands are aligned with the whole document, not just the range
+	edits = RangeFormatting(doc, lsp.Range{Start: lsp.Position{Line: 0}, End: lsp.Position{Line: 3}}, options)
+	if len(edits) != 2 || edits[0].NewText != "    ADD  X1, X2, X3" || edits[1].NewText != "    SUBI X1, X1, #1" {
+		t.Errorf("Incorrect range formatting edits %v.", edits)
+	}
+
+	edits = OnTypeFormatting(doc, lsp.Position{Line: 3, Character: 0}, "\n", options)
+	if len(edits) != 1 || edits[0].Range.Start.Line != 2 {
+		t.Errorf("Expected the previous line to be formatted, got %v.", edits)
+	}
+	if edits := OnTypeFormatting(doc, lsp.Position{Line: 0, Character: 0}, "\n", options); len(edits) != 0 {
+		t.Errorf("Expected no edits before the first line, got %v.", edits)
+	}
+}
@@ -0,0 +1,115 @@
+package languageserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	lsp "go.lsp.dev/protocol"
+
+	"server/isa"
+	"server/parser"
+)
+
+// Hover returns documentation for the token under a position in a document.
+func Hover(doc *document, position lsp.Position) *lsp.Hover {
+	token, ok := doc.tokenAt(position)
+	if !ok {
+		return nil
+	}
+
+	var contents string
+	switch token.Type {
+	case parser.InstructionToken:
+		info, ok := Instructions[token.Value]
+		if !ok {
+			return nil
+		}
+		contents = info.Markdown()
+
+		line := (*doc.tokens)[position.Line]
+		if len(parse(line, int(position.Line), parser.Expected(token))) == 0 {
+			if expansion, ok := expandPseudo(line, doc.Program()); ok {
+				contents += fmt.Sprintf("\n\n```legv8\n%s\n```", strings.Join(expansion, "\n"))
+			}
+		}
+	case parser.RegisterToken:
+		contents = registerMarkdown(token.Value)
+	case parser.LabelToken:
+		definition, ok := doc.Program().Label(token.Value)
+		if !ok {
+			contents = fmt.Sprintf("```legv8\n%s\n```\nLabel is not defined.", token.Value)
+			break
+		}
+		contents = fmt.Sprintf("```legv8\n%s:\n```\nDefined on line %d.", token.Value, definition.Range.Start.Line+1)
+	case parser.NumberToken:
+		contents = immediateMarkdown(token.Value)
+	}
+	if contents == "" {
+		return nil
+	}
+
+	return &lsp.Hover{
+		Contents: lsp.MarkupContent{
+			Kind:  lsp.Markdown,
+			Value: contents,
+		},
+		Range: &lsp.Range{
+			Start: lsp.Position{Line: position.Line, Character: uint32(token.Start)},
+			End:   lsp.Position{Line: position.Line, Character: uint32(token.End)},
+		},
+	}
+}
+
+// registerMarkdown describes the conventional use of a register.
+func registerMarkdown(name string) string {
+	if number, ok := isa.FloatRegister(name); ok {
+		precision := "Single"
+		if registerClass(name) == DoubleRegister {
+			precision = "Double"
+		}
+		return fmt.Sprintf("```legv8\n%s\n```\n%s-precision floating-point register %d", name, precision, number)
+	}
+
+	number, ok := isa.IntegerRegister(name)
+	if !ok {
+		return ""
+	}
+
+	var role string
+	switch {
+	case name == "XZR":
+		role = "Zero register, always reads as 0"
+	case number <= 7:
+		role = "Argument / result register"
+	case number == 8:
+		role = "Indirect result location register"
+	case number <= 15:
+		role = "Temporary register"
+	case number == 16:
+		role = "Intra-procedure-call scratch register (IP0)"
+	case number == 17:
+		role = "Intra-procedure-call scratch register (IP1)"
+	case number == 18:
+		role = "Platform register"
+	case number <= 27:
+		role = "Callee-saved register"
+	case number == 28:
+		role = "Stack pointer (SP)"
+	case number == 29:
+		role = "Frame pointer (FP)"
+	case number == 30:
+		role = "Link register (LR)"
+	}
+
+	return fmt.Sprintf("```legv8\n%s\n```\n%s (X%d)", name, role, number)
+}
+
+// immediateMarkdown shows an immediate in decimal, hexadecimal and binary.
+func immediateMarkdown(value string) string {
+	number, err := strconv.ParseInt(value[1:], 10, 64)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("```legv8\n%s\n```\nDecimal: `%d`\n\nHex: `0x%X`\n\nBinary: `0b%b`", value, number, number, number)
+}
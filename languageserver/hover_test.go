@@ -0,0 +1,62 @@
+package languageserver
+
+import (
+	"strings"
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+
+	"server/isa"
+	"server/parser"
+)
+
+func TestHover(t *testing.T) {
+	doc := newDocument(uri.File("/tmp/test.legv8"), "loop:\nADDI X16, X1, #12\nB loop\nB missing\nLSL X0, X0, #2\nMOV X1, X2\nMOVZ X1, #255, LSL #16\nMNEG X3, X1, X2\nADR X4, loop")
+
+	inputs := []struct {
+		position lsp.Position
+		contains []string
+	}{
+		{lsp.Position{Line: 1, Character: 2}, []string{"ADDI Rd, Rn, #ALU_immediate", "I-format", "R[Rd] = R[Rn] + ALU_immediate", "1001000100"}},
+		{lsp.Position{Line: 1, Character: 6}, []string{"IP0"}},
+		{lsp.Position{Line: 1, Character: 16}, []string{"`12`", "`0xC`", "`0b1100`"}},
+		{lsp.Position{Line: 2, Character: 3}, []string{"loop:", "line 1"}},
+		{lsp.Position{Line: 3, Character: 4}, []string{"not defined"}},
+		{lsp.Position{Line: 4, Character: 1}, []string{"I-format", "11010011011"}},
+		{lsp.Position{Line: 5, Character: 1}, []string{"Pseudo-instruction", "ORR Rd, XZR, Rm", "ORR X1, XZR, X2"}},
+		{lsp.Position{Line: 6, Character: 1}, []string{"IW-format", "110100101"}},
+		{lsp.Position{Line: 7, Character: 1}, []string{"Pseudo-instruction", "`MUL Rd, Rn, Rm`, then `SUB Rd, XZR, Rd`", "MUL X3, X1, X2\nSUB X3, XZR, X3"}},
+		{lsp.Position{Line: 8, Character: 1}, []string{"Pseudo-instruction", "ADDI Rd, XZR, #label", "ADDI X4, XZR, #0"}},
+	}
+
+	for _, in := range inputs {
+		hover := Hover(doc, in.position)
+		if hover == nil {
+			t.Errorf("Expected hover at %v.", in.position)
+			continue
+		}
+		for _, c := range in.contains {
+			if !strings.Contains(hover.Contents.Value, c) {
+				t.Errorf("Expected hover at %v to contain %q. Received %q.", in.position, c, hover.Contents.Value)
+			}
+		}
+	}
+
+	if hover := Hover(doc, lsp.Position{Line: 1, Character: 9}); hover != nil {
+		t.Errorf("Expected no hover on whitespace. Received %q.", hover.Contents.Value)
+	}
+}
+
+func TestInstructionsDocumented(t *testing.T) {
+	for mnemonic := range parser.KeywordInstructionTypes {
+		if _, ok := Instructions[mnemonic]; !ok {
+			t.Errorf("Instruction %s has no documentation.", mnemonic)
+		}
+		_, encoded := isa.Instructions[mnemonic]
+		_, pseudo := isa.PseudoInstructions[mnemonic]
+		if !encoded && !pseudo {
+			t.Errorf("Instruction %s has no encoding.", mnemonic)
+		}
+	}
+}
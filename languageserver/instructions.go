@@ -0,0 +1,186 @@
+package languageserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"server/isa"
+	"server/parser"
+)
+
+// InstructionInfo documents a single LEGv8 instruction.
+type InstructionInfo struct {
+	Mnemonic    string
+	Syntax      string
+	Description string
+	Semantics   string
+}
+
+// Instructions maps each mnemonic to its documentation.
+var Instructions map[string]*InstructionInfo
+
+type condition struct {
+	suffix      string
+	description string
+	test        string
+}
+
+var conditions = []condition{
+	{"EQ", "equal", "Z == 1"},
+	{"NE", "not equal", "Z == 0"},
+	{"HS", "unsigned higher or same", "C == 1"},
+	{"LO", "unsigned lower", "C == 0"},
+	{"MI", "minus", "N == 1"},
+	{"PL", "plus", "N == 0"},
+	{"VS", "overflow set", "V == 1"},
+	{"VC", "overflow clear", "V == 0"},
+	{"HI", "unsigned higher", "C == 1 && Z == 0"},
+	{"LS", "unsigned lower or same", "!(C == 1 && Z == 0)"},
+	{"GE", "signed greater than or equal", "N == V"},
+	{"LT", "signed less than", "N != V"},
+	{"GT", "signed greater than", "Z == 0 && N == V"},
+	{"LE", "signed less than or equal", "!(Z == 0 && N == V)"},
+}
+
+var instructionTable = []*InstructionInfo{
+	// R-format
+	{"ADD", "ADD Rd, Rn, Rm", "Add", "R[Rd] = R[Rn] + R[Rm]"},
+	{"ADDS", "ADDS Rd, Rn, Rm", "Add and set flags", "R[Rd] = R[Rn] + R[Rm], FLAGS set"},
+	{"AND", "AND Rd, Rn, Rm", "Bitwise and", "R[Rd] = R[Rn] & R[Rm]"},
+	{"ANDS", "ANDS Rd, Rn, Rm", "Bitwise and and set flags", "R[Rd] = R[Rn] & R[Rm], FLAGS set"},
+	{"EOR", "EOR Rd, Rn, Rm", "Bitwise exclusive or", "R[Rd] = R[Rn] ^ R[Rm]"},
+	{"ORR", "ORR Rd, Rn, Rm", "Bitwise inclusive or", "R[Rd] = R[Rn] | R[Rm]"},
+	{"SUB", "SUB Rd, Rn, Rm", "Subtract", "R[Rd] = R[Rn] - R[Rm]"},
+	{"SUBS", "SUBS Rd, Rn, Rm", "Subtract and set flags", "R[Rd] = R[Rn] - R[Rm], FLAGS set"},
+	{"MUL", "MUL Rd, Rn, Rm", "Multiply", "R[Rd] = (R[Rn] * R[Rm])(63:0)"},
+	{"SMULH", "SMULH Rd, Rn, Rm", "Signed multiply high", "R[Rd] = (R[Rn] * R[Rm])(127:64)"},
+	{"UMULH", "UMULH Rd, Rn, Rm", "Unsigned multiply high", "R[Rd] = (R[Rn] * R[Rm])(127:64)"},
+	{"SDIV", "SDIV Rd, Rn, Rm", "Signed divide", "R[Rd] = R[Rn] / R[Rm]"},
+	{"UDIV", "UDIV Rd, Rn, Rm", "Unsigned divide", "R[Rd] = R[Rn] / R[Rm]"},
+	{"FADDS", "FADDS Sd, Sn, Sm", "Floating-point add single", "S[Rd] = S[Rn] + S[Rm]"},
+	{"FADDD", "FADDD Dd, Dn, Dm", "Floating-point add double", "D[Rd] = D[Rn] + D[Rm]"},
+	{"FSUBS", "FSUBS Sd, Sn, Sm", "Floating-point subtract single", "S[Rd] = S[Rn] - S[Rm]"},
+	{"FSUBD", "FSUBD Dd, Dn, Dm", "Floating-point subtract double", "D[Rd] = D[Rn] - D[Rm]"},
+	{"FMULS", "FMULS Sd, Sn, Sm", "Floating-point multiply single", "S[Rd] = S[Rn] * S[Rm]"},
+	{"FMULD", "FMULD Dd, Dn, Dm", "Floating-point multiply double", "D[Rd] = D[Rn] * D[Rm]"},
+	{"FDIVS", "FDIVS Sd, Sn, Sm", "Floating-point divide single", "S[Rd] = S[Rn] / S[Rm]"},
+	{"FDIVD", "FDIVD Dd, Dn, Dm", "Floating-point divide double", "D[Rd] = D[Rn] / D[Rm]"},
+	{"FCMPS", "FCMPS Sn, Sm", "Floating-point compare single", "FLAGS = compare(S[Rn], S[Rm])"},
+	{"FCMPD", "FCMPD Dn, Dm", "Floating-point compare double", "FLAGS = compare(D[Rn], D[Rm])"},
+	{"LDURS", "LDURS St, [Rn, #DT_address]", "Load single floating-point", "S[Rt] = M[R[Rn] + DT_address]"},
+	{"LDURD", "LDURD Dt, [Rn, #DT_address]", "Load double floating-point", "D[Rt] = M[R[Rn] + DT_address]"},
+	{"STURS", "STURS St, [Rn, #DT_address]", "Store single floating-point", "M[R[Rn] + DT_address] = S[Rt]"},
+	{"STURD", "STURD Dt, [Rn, #DT_address]", "Store double floating-point", "M[R[Rn] + DT_address] = D[Rt]"},
+	{"LSL", "LSL Rd, Rn, #shamt", "Logical shift left", "R[Rd] = R[Rn] << shamt"},
+	{"LSR", "LSR Rd, Rn, #shamt", "Logical shift right", "R[Rd] = R[Rn] >>> shamt"},
+	{"BR", "BR Rt", "Branch to register", "PC = R[Rt]"},
+	{"BLR", "BLR Rt", "Branch with link to register", "R[30] = PC + 4; PC = R[Rt]"},
+	{"RET", "RET", "Return from procedure", "PC = R[30], or R[Rt] if given"},
+
+	// I-format
+	{"ADDI", "ADDI Rd, Rn, #ALU_immediate", "Add immediate", "R[Rd] = R[Rn] + ALU_immediate"},
+	{"ADDIS", "ADDIS Rd, Rn, #ALU_immediate", "Add immediate and set flags", "R[Rd] = R[Rn] + ALU_immediate, FLAGS set"},
+	{"ANDI", "ANDI Rd, Rn, #ALU_immediate", "Bitwise and immediate", "R[Rd] = R[Rn] & ALU_immediate"},
+	{"ANDIS", "ANDIS Rd, Rn, #ALU_immediate", "Bitwise and immediate and set flags", "R[Rd] = R[Rn] & ALU_immediate, FLAGS set"},
+	{"EORI", "EORI Rd, Rn, #ALU_immediate", "Bitwise exclusive or immediate", "R[Rd] = R[Rn] ^ ALU_immediate"},
+	{"ORRI", "ORRI Rd, Rn, #ALU_immediate", "Bitwise inclusive or immediate", "R[Rd] = R[Rn] | ALU_immediate"},
+	{"SUBI", "SUBI Rd, Rn, #ALU_immediate", "Subtract immediate", "R[Rd] = R[Rn] - ALU_immediate"},
+	{"SUBIS", "SUBIS Rd, Rn, #ALU_immediate", "Subtract immediate and set flags", "R[Rd] = R[Rn] - ALU_immediate, FLAGS set"},
+
+	// D-format
+	{"LDUR", "LDUR Rt, [Rn, #DT_address]", "Load register", "R[Rt] = M[R[Rn] + DT_address]"},
+	{"LDURB", "LDURB Rt, [Rn, #DT_address]", "Load byte", "R[Rt] = {56'b0, M[R[Rn] + DT_address](7:0)}"},
+	{"LDURH", "LDURH Rt, [Rn, #DT_address]", "Load half word", "R[Rt] = {48'b0, M[R[Rn] + DT_address](15:0)}"},
+	{"LDURSW", "LDURSW Rt, [Rn, #DT_address]", "Load signed word", "R[Rt] = {32{M[R[Rn] + DT_address][31]}, M[R[Rn] + DT_address](31:0)}"},
+	{"LDXR", "LDXR Rt, [Rn, #DT_address]", "Load exclusive register", "R[Rt] = M[R[Rn] + DT_address]"},
+	{"STUR", "STUR Rt, [Rn, #DT_address]", "Store register", "M[R[Rn] + DT_address] = R[Rt]"},
+	{"STURB", "STURB Rt, [Rn, #DT_address]", "Store byte", "M[R[Rn] + DT_address](7:0) = R[Rt](7:0)"},
+	{"STURH", "STURH Rt, [Rn, #DT_address]", "Store half word", "M[R[Rn] + DT_address](15:0) = R[Rt](15:0)"},
+	{"STURW", "STURW Rt, [Rn, #DT_address]", "Store word", "M[R[Rn] + DT_address](31:0) = R[Rt](31:0)"},
+	{"STXR", "STXR Rt, [Rn, #DT_address]", "Store exclusive register", "M[R[Rn] + DT_address] = R[Rt]"},
+
+	// B-format
+	{"B", "B label", "Branch", "PC = PC + BR_address"},
+	{"BL", "BL label", "Branch with link", "R[30] = PC + 4; PC = PC + BR_address"},
+
+	// CB-format
+	{"CBZ", "CBZ Rt, label", "Compare and branch if zero", "if (R[Rt] == 0) PC = PC + COND_BR_address"},
+	{"CBNZ", "CBNZ Rt, label", "Compare and branch if not zero", "if (R[Rt] != 0) PC = PC + COND_BR_address"},
+
+	// IW-format
+	{"MOVZ", "MOVZ Rd, #MOV_immediate, LSL #shift", "Move wide with zero", "R[Rd] = {MOV_immediate << shift}, other bits 0"},
+	{"MOVK", "MOVK Rd, #MOV_immediate, LSL #shift", "Move wide with keep", "R[Rd](shift+15:shift) = MOV_immediate"},
+
+	// pseudo-instructions
+	{"MOV", "MOV Rd, Rm", "Move register", "R[Rd] = R[Rm]"},
+	{"CMP", "CMP Rn, Rm", "Compare", "FLAGS = R[Rn] - R[Rm]"},
+	{"CMPI", "CMPI Rn, #ALU_immediate", "Compare immediate", "FLAGS = R[Rn] - ALU_immediate"},
+	{"LDA", "LDA Rd, [Rn, #ALU_immediate]", "Load address", "R[Rd] = R[Rn] + ALU_immediate"},
+	{"ADR", "ADR Rd, label", "Address of label", "R[Rd] = address of label"},
+	{"MNEG", "MNEG Rd, Rn, Rm", "Multiply and negate", "R[Rd] = -(R[Rn] * R[Rm])(63:0)"},
+	{"SMULL", "SMULL Rd, Rn, Rm", "Signed multiply long", "R[Rd] = (R[Rn] * R[Rm])(63:0)"},
+	{"UMULL", "UMULL Rd, Rn, Rm", "Unsigned multiply long", "R[Rd] = (R[Rn] * R[Rm])(63:0)"},
+
+	// simulator instructions
+	{"PRNT", "PRNT Rt", "Print register", "print(R[Rt])"},
+	{"PRNL", "PRNL", "Print newline", "print(\"\\n\")"},
+	{"DUMP", "DUMP", "Dump registers and memory", "dump()"},
+	{"HALT", "HALT", "Halt the program", "dump(); exit()"},
+}
+
+// formatName describes the operand format of an instruction type.
+func formatName(instructionType parser.InstructionType) string {
+	switch instructionType {
+	case parser.IGNORE:
+		return "Simulator instruction"
+	case parser.PSEUDO:
+		return "Pseudo-instruction"
+	}
+	return fmt.Sprintf("%s-format", instructionType.String())
+}
+
+// Markdown renders the documentation of an instruction for display in the editor.
+func (info *InstructionInfo) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "```legv8\n%s\n```\n", info.Syntax)
+	fmt.Fprintf(&b, "**%s** (%s)\n\n", info.Description, formatName(parser.KeywordInstructionTypes[info.Mnemonic]))
+	fmt.Fprintf(&b, "`%s`\n\n", info.Semantics)
+
+	if pseudo, ok := isa.PseudoInstructions[info.Mnemonic]; ok {
+		fmt.Fprintf(&b, "Expands to `%s`", strings.Join(pseudo.Expansion, "`, then `"))
+		return b.String()
+	}
+
+	encoding := isa.Instructions[info.Mnemonic]
+	opcode := strconv.FormatUint(uint64(encoding.Opcode), 2)
+	opcode = strings.Repeat("0", encoding.Format.OpcodeWidth()-len(opcode)) + opcode
+	fmt.Fprintf(&b, "Opcode: `%s` (0x%X)", opcode, encoding.Opcode)
+	if encoding.Shamt != 0 {
+		fmt.Fprintf(&b, ", shamt: `%06b`", encoding.Shamt)
+	}
+	if strings.HasPrefix(info.Mnemonic, "B.") {
+		fmt.Fprintf(&b, ", Rt: `%05b`", isa.ConditionCodes[strings.TrimPrefix(info.Mnemonic, "B.")])
+	}
+
+	return b.String()
+}
+
+func init() {
+	Instructions = make(map[string]*InstructionInfo)
+
+	for _, info := range instructionTable {
+		Instructions[info.Mnemonic] = info
+	}
+
+	for _, c := range conditions {
+		mnemonic := "B." + c.suffix
+		Instructions[mnemonic] = &InstructionInfo{
+			Mnemonic:    mnemonic,
+			Syntax:      mnemonic + " label",
+			Description: fmt.Sprintf("Branch if %s", c.description),
+			Semantics:   fmt.Sprintf("if (%s) PC = PC + COND_BR_address", c.test),
+		}
+	}
+}
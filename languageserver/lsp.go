@@ -7,12 +7,22 @@ import (
 	"go.lsp.dev/jsonrpc2"
 	lsp "go.lsp.dev/protocol"
 	"go.lsp.dev/uri"
+
+	"server/ast"
+	"server/parser"
 )
 
 type Server struct {
 	conn      jsonrpc2.Conn
 	workspace string
 	handlers  handlers
+	documents *documentStore
+
+	// snippetSupport is set when the client accepts snippets in completions.
+	snippetSupport bool
+
+	// settings configures the rules diagnostics are reported for.
+	settings RuleSettings
 }
 
 // handler is a jsonrpc2.Handler with a custom logger.
@@ -27,7 +37,8 @@ type handlers map[string]handler
 // NewServer creates a new language server.
 func NewServer(conn jsonrpc2.Conn) *Server {
 	s := &Server{
-		conn: conn,
+		conn:      conn,
+		documents: newDocumentStore(),
 	}
 	s.buildHandlers()
 	return s
@@ -35,11 +46,29 @@ func NewServer(conn jsonrpc2.Conn) *Server {
 
 func (s *Server) buildHandlers() {
 	s.handlers = map[string]handler{
-		lsp.MethodInitialize:                     s.handleInitialize,
-		lsp.MethodTextDocumentDidOpen:            s.handleDocumentOpen,
-		lsp.MethodWorkspaceDidChangeWatchedFiles: s.handleWatchedFileChange,
-		lsp.MethodTextDocumentDidChange:          s.handleDocumentChange,
-		lsp.MethodTextDocumentDidSave:            s.handleDocumentSave,
+		lsp.MethodInitialize:                      s.handleInitialize,
+		lsp.MethodTextDocumentDidOpen:             s.handleDocumentOpen,
+		lsp.MethodWorkspaceDidChangeWatchedFiles:  s.handleWatchedFileChange,
+		lsp.MethodWorkspaceDidChangeConfiguration: s.handleConfigurationChange,
+		lsp.MethodTextDocumentDidChange:           s.handleDocumentChange,
+		lsp.MethodTextDocumentDidSave:             s.handleDocumentSave,
+		lsp.MethodTextDocumentDidClose:            s.handleDocumentClose,
+		lsp.MethodTextDocumentHover:               s.handleHover,
+		lsp.MethodTextDocumentCompletion:          s.handleCompletion,
+		lsp.MethodTextDocumentSignatureHelp:       s.handleSignatureHelp,
+		lsp.MethodTextDocumentDefinition:          s.handleDefinition,
+		lsp.MethodTextDocumentReferences:          s.handleReferences,
+		lsp.MethodTextDocumentPrepareRename:       s.handlePrepareRename,
+		lsp.MethodTextDocumentRename:              s.handleRename,
+		lsp.MethodTextDocumentDocumentSymbol:      s.handleDocumentSymbol,
+		lsp.MethodWorkspaceSymbol:                 s.handleWorkspaceSymbol,
+		lsp.MethodTextDocumentCodeAction:          s.handleCodeAction,
+		lsp.MethodTextDocumentFormatting:          s.handleFormatting,
+		lsp.MethodTextDocumentRangeFormatting:     s.handleRangeFormatting,
+		lsp.MethodTextDocumentOnTypeFormatting:    s.handleOnTypeFormatting,
+		lsp.MethodSemanticTokensFull:              s.handleSemanticTokensFull,
+		lsp.MethodSemanticTokensFullDelta:         s.handleSemanticTokensDelta,
+		lsp.MethodSemanticTokensRange:             s.handleSemanticTokensRange,
 	}
 }
 
@@ -60,6 +89,10 @@ func (s *Server) handleInitialize(
 	type initParams struct {
 		ProcessID int    `json:"processId,omitempty"`
 		RootURI   string `json:"rootUri,omitempty"`
+
+		Capabilities lsp.ClientCapabilities `json:"capabilities,omitempty"`
+
+		InitializationOptions *RuleSettings `json:"initializationOptions,omitempty"`
 	}
 
 	var params initParams
@@ -68,17 +101,64 @@ func (s *Server) handleInitialize(
 	}
 
 	s.workspace = string(uri.New(params.RootURI).Filename())
+	if td := params.Capabilities.TextDocument; td != nil && td.Completion != nil && td.Completion.CompletionItem != nil {
+		s.snippetSupport = td.Completion.CompletionItem.SnippetSupport
+	}
+	if params.InitializationOptions != nil {
+		s.configure(ctx, *params.InitializationOptions)
+	}
 	reply(ctx, lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
 			// if we support `goto` definition.
-			DefinitionProvider: false,
+			DefinitionProvider: true,
+
+			// if we support finding references to labels.
+			ReferencesProvider: true,
+
+			// if we support renaming labels, checking the label before renaming.
+			RenameProvider: &lsp.RenameOptions{
+				PrepareProvider: true,
+			},
 
 			// If we support `hover` info.
-			HoverProvider: false,
+			HoverProvider: true,
+
+			// Complete mnemonics, registers and labels.
+			CompletionProvider: &lsp.CompletionOptions{
+				TriggerCharacters: []string{" ", ",", "["},
+			},
+
+			// List the labels of a document or of the whole workspace.
+			DocumentSymbolProvider:  true,
+			WorkspaceSymbolProvider: true,
+
+			// Offer quick fixes for diagnostics.
+			CodeActionProvider: &lsp.CodeActionOptions{
+				CodeActionKinds: []lsp.CodeActionKind{lsp.QuickFix},
+			},
+
+			// Format whole documents, selections and each line as it is finished.
+			DocumentFormattingProvider:      true,
+			DocumentRangeFormattingProvider: true,
+			DocumentOnTypeFormattingProvider: &lsp.DocumentOnTypeFormattingOptions{
+				FirstTriggerCharacter: "\n",
+			},
+
+			// Highlight instructions by format and special registers.
+			SemanticTokensProvider: semanticTokensOptions{
+				Legend: semanticTokensLegend(),
+				Range:  true,
+				Full:   semanticTokensFullOptions{Delta: true},
+			},
+
+			// Show the operands of an instruction while they are typed.
+			SignatureHelpProvider: &lsp.SignatureHelpOptions{
+				TriggerCharacters: []string{" ", ","},
+			},
 
 			TextDocumentSync: lsp.TextDocumentSyncOptions{
-				// Send all file content on every change (can be optimized later).
-				Change: lsp.TextDocumentSyncKindFull,
+				// Only send the ranges of the file that changed.
+				Change: lsp.TextDocumentSyncKindIncremental,
 
 				// if we want to be notified about open/close of Terramate files.
 				OpenClose: true,
@@ -98,6 +178,45 @@ func (s *Server) handleInitialize(
 	return nil
 }
 
+// handleConfigurationChange applies the settings under the "legv8" section
+// and rechecks every open document.
+func (s *Server) handleConfigurationChange(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	type configurationParams struct {
+		Settings struct {
+			LEGv8 RuleSettings `json:"legv8"`
+		} `json:"settings"`
+	}
+
+	var params configurationParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return err
+	}
+
+	s.configure(ctx, params.Settings.LEGv8)
+	for _, u := range s.documents.uris() {
+		diagnose(u, ctx, s)
+	}
+
+	return nil
+}
+
+// configure replaces the rule settings, keeping the current ones if the new
+// settings name an unknown rule or severity.
+func (s *Server) configure(ctx context.Context, settings RuleSettings) {
+	if err := settings.Validate(); err != nil {
+		s.conn.Notify(ctx, lsp.MethodWindowShowMessage, lsp.ShowMessageParams{
+			Message: "Invalid legv8 settings: " + err.Error(),
+			Type:    lsp.MessageTypeError,
+		})
+		return
+	}
+	s.settings = settings
+}
+
 func (s *Server) handleWatchedFileChange(
 	ctx context.Context,
 	reply jsonrpc2.Replier,
@@ -123,6 +242,7 @@ func (s *Server) handleDocumentOpen(
 		return err
 	}
 
+	s.documents.open(params.TextDocument.URI, params.TextDocument.Text)
 	diagnose(params.TextDocument.URI, ctx, s)
 
 	return nil
@@ -133,11 +253,17 @@ func (s *Server) handleDocumentChange(
 	reply jsonrpc2.Replier,
 	r jsonrpc2.Request,
 ) error {
-	var params lsp.DidChangeTextDocumentParams
+	type changeParams struct {
+		TextDocument   lsp.VersionedTextDocumentIdentifier `json:"textDocument"`
+		ContentChanges []contentChange                     `json:"contentChanges"`
+	}
+
+	var params changeParams
 	if err := json.Unmarshal(r.Params(), &params); err != nil {
 		return err
 	}
 
+	s.documents.update(params.TextDocument.URI, params.ContentChanges)
 	diagnose(params.TextDocument.URI, ctx, s)
 
 	return nil
@@ -158,12 +284,346 @@ func (s *Server) handleDocumentSave(
 	return nil
 }
 
+func (s *Server) handleDocumentClose(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.DidCloseTextDocumentParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return err
+	}
+
+	s.documents.close(params.TextDocument.URI)
+
+	// clear any diagnostics left behind for the closed document
+	s.conn.Notify(ctx, lsp.MethodTextDocumentPublishDiagnostics, lsp.PublishDiagnosticsParams{
+		URI:         params.TextDocument.URI,
+		Diagnostics: []lsp.Diagnostic{},
+	})
+
+	return nil
+}
+
+func (s *Server) handleHover(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.HoverParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, Hover(doc, params.Position), nil)
+}
+
+func (s *Server) handleCompletion(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.CompletionParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, Completion(doc, params.Position, s.snippetSupport), nil)
+}
+
+func (s *Server) handleSignatureHelp(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.SignatureHelpParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, SignatureHelp(doc, params.Position), nil)
+}
+
+func (s *Server) handleDefinition(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.DefinitionParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, Definition(doc, params.Position), nil)
+}
+
+func (s *Server) handleReferences(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.ReferenceParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, References(doc, params.Position, params.Context.IncludeDeclaration), nil)
+}
+
+func (s *Server) handlePrepareRename(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.PrepareRenameParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	result, err := PrepareRename(doc, params.Position)
+	if err != nil {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, err.Error()))
+	}
+	return reply(ctx, result, nil)
+}
+
+func (s *Server) handleRename(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.RenameParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	result, err := Rename(doc, params.Position, params.NewName)
+	if err != nil {
+		return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InvalidRequest, err.Error()))
+	}
+	return reply(ctx, result, nil)
+}
+
+func (s *Server) handleDocumentSymbol(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.DocumentSymbolParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, DocumentSymbols(doc), nil)
+}
+
+func (s *Server) handleWorkspaceSymbol(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.WorkspaceSymbolParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	return reply(ctx, WorkspaceSymbols(s.workspace, params.Query, s.documents), nil)
+}
+
+func (s *Server) handleCodeAction(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.CodeActionParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, CodeActions(doc, params.Context.Diagnostics), nil)
+}
+
+func (s *Server) handleFormatting(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.DocumentFormattingParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, Formatting(doc, formatOptions(params.Options)), nil)
+}
+
+func (s *Server) handleRangeFormatting(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.DocumentRangeFormattingParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, RangeFormatting(doc, params.Range, formatOptions(params.Options)), nil)
+}
+
+func (s *Server) handleOnTypeFormatting(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.DocumentOnTypeFormattingParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, OnTypeFormatting(doc, params.Position, params.Ch, formatOptions(params.Options)), nil)
+}
+
+func (s *Server) handleSemanticTokensFull(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.SemanticTokensParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	data := SemanticTokens(doc)
+	return reply(ctx, lsp.SemanticTokens{ResultID: doc.nextSemanticResult(data), Data: data}, nil)
+}
+
+func (s *Server) handleSemanticTokensDelta(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.SemanticTokensDeltaParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	data := SemanticTokens(doc)
+	previous := doc.semantic
+	id := doc.nextSemanticResult(data)
+
+	// without the previous result to diff against, send every token again
+	if previous == nil || previous.id != params.PreviousResultID {
+		return reply(ctx, lsp.SemanticTokens{ResultID: id, Data: data}, nil)
+	}
+	return reply(ctx, lsp.SemanticTokensDelta{
+		ResultID: id,
+		Edits:    []lsp.SemanticTokensEdit{semanticTokensEdit(previous.data, data)},
+	}, nil)
+}
+
+func (s *Server) handleSemanticTokensRange(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	r jsonrpc2.Request,
+) error {
+	var params lsp.SemanticTokensRangeParams
+	if err := json.Unmarshal(r.Params(), &params); err != nil {
+		return reply(ctx, nil, jsonrpc2.ErrInvalidParams)
+	}
+
+	doc, ok := s.documents.get(params.TextDocument.URI)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+
+	return reply(ctx, lsp.SemanticTokens{Data: SemanticTokensRange(doc, params.Range)}, nil)
+}
+
 func diagnose(uri uri.URI, ctx context.Context, server *Server) {
-	tokenizedLines := TokenizeFile(uri)
-	diagnostics := Parse(tokenizedLines)
+	// prefer the live contents of open documents over what is saved on disk
+	var tokenizedLines *[]*[]*parser.Token
+	var program *ast.Program
+	if doc, ok := server.documents.get(uri); ok {
+		tokenizedLines, program = doc.tokens, doc.Program()
+	} else if tokenizedLines = TokenizeFile(uri); tokenizedLines != nil {
+		program = parser.BuildProgram(tokenizedLines)
+	}
+	if tokenizedLines == nil {
+		return
+	}
+	diagnostics := server.settings.Apply(*Analyze(uri, tokenizedLines, program))
 
 	server.conn.Notify(ctx, lsp.MethodTextDocumentPublishDiagnostics, lsp.PublishDiagnosticsParams{
 		URI:         uri,
-		Diagnostics: *diagnostics,
+		Diagnostics: diagnostics,
 	})
 }
@@ -1,14 +1,17 @@
 package languageserver
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"strings"
 
 	lsp "go.lsp.dev/protocol"
+
+	"server/parser"
 )
 
-func Parse(tokens *[]*[]*Token) *[]lsp.Diagnostic {
+func Parse(tokens *[]*[]*parser.Token) *[]lsp.Diagnostic {
 	diagnostics := []lsp.Diagnostic{}
 
 	// parse each line, reporting diagnostics when issues found
@@ -18,88 +21,268 @@ func Parse(tokens *[]*[]*Token) *[]lsp.Diagnostic {
 		}
 		lineType := (*tokens)[0].Type
 
-		// if there is a label token and colon token, this is a label. continue as no error found
-		if lineType == LabelToken && len(*tokens) == 2 && (*tokens)[1].Type == ColonToken {
+		// if there is a label token and colon token, this is a label. continue as no error found.
+		// labels named after instructions are reported by the semantic checks.
+		if (lineType == parser.LabelToken || lineType == parser.InstructionToken) && len(*tokens) == 2 && (*tokens)[1].Type == parser.ColonToken {
 			continue
 		}
 
 		// since not a label, expect instruction
-		if lineType != InstructionToken {
+		if lineType != parser.InstructionToken {
+			word, end := leadingWord(*tokens)
+			message := "Expected an instruction keyword."
+			if lineType == parser.LabelToken {
+				message += didYouMean(suggestMnemonics(word))
+			}
 			diagnostics = append(diagnostics, lsp.Diagnostic{
 				Range: lsp.Range{
-					Start: lsp.Position{Line: uint32(i), Character: 0},
-					End:   lsp.Position{Line: uint32(i), Character: uint32(len((*tokens)[0].Value))},
+					Start: lsp.Position{Line: uint32(i), Character: uint32((*tokens)[0].Start)},
+					End:   lsp.Position{Line: uint32(i), Character: uint32(end)},
 				},
 				Severity: lsp.DiagnosticSeverityError,
-				Message:  "Expected an instruction keyword.",
+				Code:     RuleExpectedInstruction,
+				Message:  message,
 				Source:   "compiler",
 			})
 			continue
 		}
 
-		instructionType := (*tokens)[0].InstructionType
-		result := parse(tokens, i, expected[instructionType])
-		if result != nil {
-			diagnostics = append(diagnostics, *result)
+		results := parse(tokens, i, parser.Expected((*tokens)[0]))
+		if len(results) > 0 {
+			diagnostics = append(diagnostics, results...)
+			continue
 		}
+		diagnostics = append(diagnostics, checkRegisters(tokens, i)...)
 	}
 
 	return &diagnostics
 }
 
-func parse(tokens *[]*Token, lineNumber int, expected *[]TokenType) *lsp.Diagnostic {
-	for i, token := range *tokens {
-		if i >= len(*expected) {
-			return &lsp.Diagnostic{
-				Range: lsp.Range{
-					Start: lsp.Position{Line: uint32(lineNumber), Character: uint32(token.Start)},
-					End:   lsp.Position{Line: uint32(lineNumber), Character: math.MaxUint32},
-				},
-				Severity: lsp.DiagnosticSeverityError,
-				Message:  "Expected end of line.",
-				Source:   "compiler",
-			}
+// syntaxData is the machine-readable detail of a syntax diagnostic, which code
+// actions read rather than the message. Found is empty when nothing is in the
+// expected token's place.
+type syntaxData struct {
+	Expected    string   `json:"expected"`
+	Found       string   `json:"found,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// dataOf returns the syntax detail of a diagnostic. Diagnostics sent back by
+// a client carry it as decoded JSON rather than as a syntaxData.
+func dataOf(diagnostic lsp.Diagnostic) (syntaxData, bool) {
+	if data, ok := diagnostic.Data.(syntaxData); ok {
+		return data, true
+	}
+	data := syntaxData{}
+	raw, err := json.Marshal(diagnostic.Data)
+	if err != nil || diagnostic.Data == nil {
+		return data, false
+	}
+	return data, json.Unmarshal(raw, &data) == nil && data.Expected != ""
+}
+
+// parse reports the mismatches between a line and the tokens expected for
+// its instruction.
+func parse(tokens *[]*parser.Token, lineNumber int, expected *[]parser.TokenType) []lsp.Diagnostic {
+	diagnostics := []lsp.Diagnostic{}
+	report := func(start, end int, rule, message string, data syntaxData) {
+		diagnostics = append(diagnostics, lsp.Diagnostic{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: uint32(lineNumber), Character: uint32(start)},
+				End:   lsp.Position{Line: uint32(lineNumber), Character: uint32(end)},
+			},
+			Severity: lsp.DiagnosticSeverityError,
+			Code:     rule,
+			Message:  message,
+			Source:   "compiler",
+			Data:     data,
+		})
+	}
+
+	for _, mismatch := range parser.Check(tokens, expected) {
+		want, token := mismatch.Expected, mismatch.Token
+		if token == nil {
+			// the line ends before the expected token
+			start := (*tokens)[len(*tokens)-1].End
+			report(start, start+1, missingRule(want), missingMessage(want), syntaxData{Expected: want.String()})
+			continue
 		}
-		if token.Type != (*expected)[i] {
-			return &lsp.Diagnostic{
-				Range: lsp.Range{
-					Start: lsp.Position{Line: uint32(lineNumber), Character: uint32(token.Start)},
-					End:   lsp.Position{Line: uint32(lineNumber), Character: uint32(token.End)},
-				},
-				Severity: lsp.DiagnosticSeverityError,
-				Message:  fmt.Sprintf("Expected a %s.", strings.ToLower((*expected)[i].String())),
-				Source:   "compiler",
-			}
+
+		data := syntaxData{Expected: want.String(), Found: token.Type.String()}
+		switch mismatch.Kind {
+		case parser.Missing:
+			report(token.Start, token.End, missingRule(want), missingMessage(want), data)
+		case parser.WrongOperand:
+			message := fmt.Sprintf("Expected %s, found %s.", withArticle(want.String()), withArticle(token.Type.String()))
+			data.Suggestions = operandSuggestions((*tokens)[0].Value, want, token, mismatch.Last)
+			report(token.Start, token.End, RuleOperandType, message+didYouMean(data.Suggestions), data)
+		case parser.UnexpectedBracket:
+			report(token.Start, token.End, RuleUnbalancedBrackets, fmt.Sprintf("Unbalanced brackets: unexpected %s.", strings.ToLower(token.Type.String())), data)
+		case parser.Trailing:
+			report(token.Start, math.MaxUint32, RuleTrailingTokens, "Expected end of line.", data)
 		}
 	}
 
-	if len(*expected) != len(*tokens) {
-		start := uint32((*tokens)[len(*tokens)-1].End)
-		return &lsp.Diagnostic{
+	return diagnostics
+}
+
+// operandSuggestions suggests how to correct an operand of the wrong kind: the
+// register a label-like word stands for, or the form of the instruction that
+// takes an immediate in place of its last register.
+func operandSuggestions(mnemonic string, want parser.TokenType, token *parser.Token, last bool) []string {
+	if want != parser.RegisterToken {
+		return nil
+	}
+	switch token.Type {
+	case parser.LabelToken:
+		return suggestRegisters(token.Value)
+	case parser.NumberToken:
+		if form, ok := immediateForms[mnemonic]; ok && last {
+			return []string{form}
+		}
+	}
+	return nil
+}
+
+// leadingWord returns the word a line starts with and the offset it ends at.
+// The tokenizer splits words it does not recognize, such as "b.eq", so tokens
+// that are not separated by spaces are joined.
+func leadingWord(tokens []*parser.Token) (string, int) {
+	word, end := tokens[0].Value, tokens[0].End
+	for _, token := range tokens[1:] {
+		if token.Start != end || token.Type == parser.CommaToken {
+			break
+		}
+		word, end = word+token.Value, token.End
+	}
+	return word, end
+}
+
+// missingMessage describes an expected token that is not present.
+func missingMessage(want parser.TokenType) string {
+	switch {
+	case parser.IsBracket(want):
+		return fmt.Sprintf("Unbalanced brackets: expected %s.", withArticle(want.String()))
+	case parser.IsPunctuation(want):
+		return fmt.Sprintf("Expected %s.", withArticle(want.String()))
+	}
+	return fmt.Sprintf("Missing %s operand.", strings.ToLower(want.String()))
+}
+
+// missingRule is the rule reported for an expected token that is not present.
+func missingRule(want parser.TokenType) string {
+	switch {
+	case parser.IsBracket(want):
+		return RuleUnbalancedBrackets
+	case parser.IsPunctuation(want):
+		return RuleExpectedComma
+	}
+	return RuleMissingOperand
+}
+
+// withArticle lowercases the name of a token type and prefixes it with "a" or "an".
+func withArticle(name string) string {
+	name = strings.ToLower(name)
+	if strings.ContainsRune("aeiou", rune(name[0])) {
+		return "an " + name
+	}
+	return "a " + name
+}
+
+// checkRegisters reports registers that do not exist, such as X32, and
+// register operands of the wrong class, such as an integer register in a
+// floating-point instruction.
+func checkRegisters(tokens *[]*parser.Token, lineNumber int) []lsp.Diagnostic {
+	diagnostics := []lsp.Diagnostic{}
+	mnemonic := (*tokens)[0].Value
+	report := func(token *parser.Token, rule, message string) {
+		diagnostics = append(diagnostics, lsp.Diagnostic{
 			Range: lsp.Range{
-				Start: lsp.Position{Line: uint32(lineNumber), Character: start},
-				End:   lsp.Position{Line: uint32(lineNumber), Character: start + 1},
+				Start: lsp.Position{Line: uint32(lineNumber), Character: uint32(token.Start)},
+				End:   lsp.Position{Line: uint32(lineNumber), Character: uint32(token.End)},
 			},
 			Severity: lsp.DiagnosticSeverityError,
-			Message:  fmt.Sprintf("Expected a %s.", strings.ToLower((*expected)[len(*tokens)].String())),
+			Code:     rule,
+			Message:  message,
 			Source:   "compiler",
+		})
+	}
+
+	n := 0
+	for _, token := range *tokens {
+		if token.Type != parser.RegisterToken {
+			continue
+		}
+		want := expectedRegisterClass(mnemonic, n)
+		n++
+		if !isRegisterName(token.Value) {
+			report(token, RuleUnknownRegister, fmt.Sprintf("Unknown register %s.", token.Value)+didYouMean(suggestRegisters(token.Value)))
+			continue
+		}
+		if registerClass(token.Value) != want {
+			report(token, RuleRegisterClass, fmt.Sprintf("Expected %s.", withArticle(want.String())))
 		}
 	}
 
-	return nil
+	return diagnostics
 }
 
-var expected map[InstructionType]*[]TokenType
+// operandDocs describes each operand of the shapes in expected, in order. The
+// names of the operands are those in each instruction's Syntax.
+var operandDocs map[parser.InstructionType][]string
+
+// mnemonicOperandDocs overrides operandDocs as mnemonicExpected overrides expected.
+var mnemonicOperandDocs map[string][]string
+
+// operandDocsFor returns the descriptions of the operands of an instruction.
+func operandDocsFor(mnemonic string) []string {
+	if docs, ok := mnemonicOperandDocs[mnemonic]; ok {
+		return docs
+	}
+	return operandDocs[parser.KeywordInstructionTypes[mnemonic]]
+}
 
 func init() {
-	expected = map[InstructionType](*[]TokenType){
-		R:      &[]TokenType{InstructionToken, RegisterToken, CommaToken, RegisterToken, CommaToken, RegisterToken},
-		I:      &[]TokenType{InstructionToken, RegisterToken, CommaToken, RegisterToken, CommaToken, NumberToken},
-		IM:     &[]TokenType{InstructionToken, RegisterToken},
-		D:      &[]TokenType{InstructionToken, RegisterToken, CommaToken, LeftBracketToken, RegisterToken, CommaToken, NumberToken, RightBracketToken},
-		B:      &[]TokenType{InstructionToken, LabelToken},
-		BR:     &[]TokenType{InstructionToken, RegisterToken},
-		CB:     &[]TokenType{InstructionToken, RegisterToken, CommaToken, LabelToken},
-		IGNORE: &[]TokenType{InstructionToken},
+	const (
+		destination   = "Register the result is written to."
+		first         = "Register holding the first operand."
+		second        = "Register holding the second operand."
+		base          = "Register holding the base address."
+		aluImmediate  = "Unsigned 12-bit immediate, 0 to 4095."
+		branchLabel   = "Label of the instruction to branch to."
+		shiftAmount   = "Number of bits to shift by, 0 to 63."
+		compared      = "Register compared with zero."
+		memoryOffset  = "Signed 9-bit byte offset from the base address, -256 to 255."
+		transferred   = "Register loaded from or stored to memory."
+		movImmediate  = "Unsigned 16-bit immediate, 0 to 65535."
+		movShift      = "Left shift applied to the immediate: 0, 16, 32 or 48."
+		printed       = "Register whose value is printed."
+		branchAddress = "Register holding the address to branch to."
+	)
+	operandDocs = map[parser.InstructionType][]string{
+		parser.R:  {destination, first, second},
+		parser.I:  {destination, first, aluImmediate},
+		parser.IM: {printed},
+		parser.D:  {transferred, base, memoryOffset},
+		parser.B:  {branchLabel},
+		parser.BR: {branchAddress},
+		parser.CB: {compared, branchLabel},
+		parser.IW: {destination, movImmediate, movShift},
+	}
+
+	mnemonicOperandDocs = map[string][]string{
+		"LSL":   {destination, first, shiftAmount},
+		"LSR":   {destination, first, shiftAmount},
+		"FCMPS": {first, second},
+		"FCMPD": {first, second},
+		"MOV":   {destination, "Register holding the value to move."},
+		"CMP":   {first, second},
+		"CMPI":  {first, aluImmediate},
+		"LDA":   {destination, base, "Unsigned 12-bit offset added to the base address, 0 to 4095."},
+		"ADR":   {destination, "Label whose address is loaded. The address must be 4095 or less."},
+		"MNEG":  operandDocs[parser.R],
+		"SMULL": operandDocs[parser.R],
+		"UMULL": operandDocs[parser.R],
+		"RET":   {},
 	}
 }
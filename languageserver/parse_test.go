@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	lsp "go.lsp.dev/protocol"
+
+	"server/parser"
 )
 
 func TestParse(t *testing.T) {
@@ -23,92 +25,116 @@ func TestParse(t *testing.T) {
 		"AND X12, X10, XZR",
 		"AND X12, X10, SP",
 		"LDUR SP, [X2, #0]",
+		"FADDS X0, S1, S2",
+		"ADD X0, D1, X2",
+		"LDURD S0, [X1, #8]",
+		"FADDS S0, S1, S2",
+		"FCMPD D0, D1",
+		"LDURS S0, [X1, #4]",
+		"ADDI X0 X1 #99999 junk",
+		"ADD X1, , X2",
+		"ADD X1, #2, X3",
+		"ADD X1, X2]",
+		"LDUR X1, [X2, X3, #0]",
+		"CBZ #1, X2",
 	}
 
-	expected_outs := []*lsp.Diagnostic{
+	expected_outs := [][]lsp.Diagnostic{
 		{
-			Severity: lsp.DiagnosticSeverityError,
-			Range: lsp.Range{
-				Start: lsp.Position{
-					Line:      0,
-					Character: 8,
-				},
-				End: lsp.Position{
-					Line:      0,
-					Character: 10,
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 8,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 10,
+					},
 				},
+				Message: "Expected a comma.",
 			},
-			Message: "Expected a comma.",
 		},
 		{
-			Severity: lsp.DiagnosticSeverityError,
-			Range: lsp.Range{
-				Start: lsp.Position{
-					Line:      0,
-					Character: 17,
-				},
-				End: lsp.Position{
-					Line:      0,
-					Character: math.MaxUint32,
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 17,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: math.MaxUint32,
+					},
 				},
+				Message: "Expected end of line.",
 			},
-			Message: "Expected end of line.",
 		},
 		{
-			Severity: lsp.DiagnosticSeverityError,
-			Range: lsp.Range{
-				Start: lsp.Position{
-					Line:      0,
-					Character: 0,
-				},
-				End: lsp.Position{
-					Line:      0,
-					Character: 3,
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 0,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 3,
+					},
 				},
+				Message: "Expected an instruction keyword.",
 			},
-			Message: "Expected an instruction keyword.",
 		},
 		{
-			Severity: lsp.DiagnosticSeverityError,
-			Range: lsp.Range{
-				Start: lsp.Position{
-					Line:      0,
-					Character: 12,
-				},
-				End: lsp.Position{
-					Line:      0,
-					Character: 13,
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 12,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 13,
+					},
 				},
+				Message: "Missing immediate operand.",
 			},
-			Message: "Expected a immediate.",
 		},
 		{
-			Severity: lsp.DiagnosticSeverityError,
-			Range: lsp.Range{
-				Start: lsp.Position{
-					Line:      0,
-					Character: 9,
-				},
-				End: lsp.Position{
-					Line:      0,
-					Character: 11,
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 9,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 11,
+					},
 				},
+				Message: "Unbalanced brackets: expected a left bracket.",
 			},
-			Message: "Expected a left bracket.",
 		},
 		{
-			Severity: lsp.DiagnosticSeverityError,
-			Range: lsp.Range{
-				Start: lsp.Position{
-					Line:      0,
-					Character: 16,
-				},
-				End: lsp.Position{
-					Line:      0,
-					Character: 17,
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 16,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 17,
+					},
 				},
+				Message: "Unbalanced brackets: expected a right bracket.",
 			},
-			Message: "Expected a right bracket.",
 		},
 		nil,
 		nil,
@@ -118,41 +144,339 @@ func TestParse(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		{
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 6,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 8,
+					},
+				},
+				Message: "Expected a single-precision register.",
+			},
+		},
+		{
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 8,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 10,
+					},
+				},
+				Message: "Expected an integer register.",
+			},
+		},
+		{
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 6,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 8,
+					},
+				},
+				Message: "Expected a double-precision register.",
+			},
+		},
+		nil,
+		nil,
+		nil,
+		{
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 8,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 10,
+					},
+				},
+				Message: "Expected a comma.",
+			},
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 11,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 17,
+					},
+				},
+				Message: "Expected a comma.",
+			},
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 18,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: math.MaxUint32,
+					},
+				},
+				Message: "Expected end of line.",
+			},
+		},
+		{
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 8,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 9,
+					},
+				},
+				Message: "Missing register operand.",
+			},
+		},
+		{
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 8,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 10,
+					},
+				},
+				Message: "Expected a register, found an immediate.",
+			},
+		},
+		{
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 10,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 11,
+					},
+				},
+				Message: "Unbalanced brackets: unexpected right bracket.",
+			},
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 11,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 12,
+					},
+				},
+				Message: "Expected a comma.",
+			},
+		},
+		{
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 14,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 16,
+					},
+				},
+				Message: "Expected an immediate, found a register.",
+			},
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 16,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 17,
+					},
+				},
+				Message: "Unbalanced brackets: expected a right bracket.",
+			},
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 16,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: math.MaxUint32,
+					},
+				},
+				Message: "Expected end of line.",
+			},
+		},
+		{
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 4,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 6,
+					},
+				},
+				Message: "Expected a register, found an immediate.",
+			},
+			{
+				Severity: lsp.DiagnosticSeverityError,
+				Range: lsp.Range{
+					Start: lsp.Position{
+						Line:      0,
+						Character: 8,
+					},
+					End: lsp.Position{
+						Line:      0,
+						Character: 10,
+					},
+				},
+				Message: "Expected a label, found a register.",
+			},
+		},
 	}
 
 	for i, in := range inputs {
-		tokens := []*[]*Token{
-			TokenizeLine(in),
+		tokens := []*[]*parser.Token{
+			parser.TokenizeLine(in),
 		}
 		out := Parse(&tokens)
 
-		if len(*out) == 0 && expected_outs[i] != nil {
-			t.Errorf("Issue not detected when parsing input: %s.", in)
+		if len(*out) != len(expected_outs[i]) {
+			t.Errorf("Expected %d diagnostics, found %d when parsing input: %s. Out = %v", len(expected_outs[i]), len(*out), in, out)
 			continue
 		}
 
-		if len(*out) > 0 && expected_outs[i] == nil {
-			t.Errorf("Issue detected when no issue present when parsing input: %s. Out = %v", in, out)
-			continue
-		}
-		if len(*out) == 0 && expected_outs[i] == nil {
-			continue
+		for j, expect := range expected_outs[i] {
+			actual := (*out)[j]
+			if expect.Message != actual.Message {
+				t.Errorf("(diagnostic=%d) Expected message '%s'. Recieved '%s'. Input: %s", j, expect.Message, actual.Message, in)
+			}
+			if expect.Range.Start.Character != actual.Range.Start.Character {
+				t.Errorf("(diagnostic=%d) Expected start character %d. Recieved %d. Input: %s", j, expect.Range.Start.Character, actual.Range.Start.Character, in)
+			}
+			if expect.Range.Start.Line != actual.Range.Start.Line {
+				t.Errorf("(diagnostic=%d) Expected start line %d. Recieved %d. Input: %s", j, expect.Range.Start.Line, actual.Range.Start.Line, in)
+			}
+			if expect.Range.End.Character != actual.Range.End.Character {
+				t.Errorf("(diagnostic=%d) Expected end character %d. Recieved %d. Input: %s", j, expect.Range.End.Character, actual.Range.End.Character, in)
+			}
+			if expect.Range.End.Line != actual.Range.End.Line {
+				t.Errorf("(diagnostic=%d) Expected end line %d. Recieved %d. Input: %s", j, expect.Range.End.Line, actual.Range.End.Line, in)
+			}
 		}
+	}
+}
 
-		if expected_outs[i].Message != (*out)[0].Message {
-			t.Errorf("Expected message '%s'. Recieved '%s'. Input: %s", expected_outs[i].Message, (*out)[0].Message, in)
-		}
-		if expected_outs[i].Range.Start.Character != (*out)[0].Range.Start.Character {
-			t.Errorf("Expected start character %d. Recieved %d. Input: %s", expected_outs[i].Range.Start.Character, (*out)[0].Range.Start.Character, in)
+func TestParseRules(t *testing.T) {
+	inputs := []struct {
+		line  string
+		rules []string
+	}{
+		{"ZZZ", []string{RuleExpectedInstruction}},
+		{"ADDI X0 X1, #12", []string{RuleExpectedComma}},
+		{"LDUR X1, [X2, #0", []string{RuleUnbalancedBrackets}},
+		{"ADD X1, , X2", []string{RuleMissingOperand}},
+		{"ADD X1, #2, X3", []string{RuleOperandType}},
+		{"SUBI X1, XZR, #9 uh oh", []string{RuleTrailingTokens}},
+		{"FADDS X0, S1, S2", []string{RuleRegisterClass}},
+		{"LDUR X0, [X1]", []string{RuleMissingOperand}},
+		{"LDUR X0, [X1, ]", []string{RuleMissingOperand}},
+		{"LDUR X0, [X1] junk", []string{RuleMissingOperand, RuleTrailingTokens}},
+		{"BLR X1", nil},
+		{"BLR", []string{RuleMissingOperand}},
+		{"RET", nil},
+		{"RET X30", nil},
+		{"RET X1, X2", []string{RuleTrailingTokens}},
+	}
+
+	for _, in := range inputs {
+		tokens := []*[]*parser.Token{parser.TokenizeLine(in.line)}
+		out := *Parse(&tokens)
+		if len(out) != len(in.rules) {
+			t.Errorf("Expected %d diagnostics, found %d. Input: %s", len(in.rules), len(out), in.line)
+			continue
 		}
-		if expected_outs[i].Range.Start.Line != (*out)[0].Range.Start.Line {
-			t.Errorf("Expected start line %d. Recieved %d. Input: %s", expected_outs[i].Range.Start.Line, (*out)[0].Range.Start.Line, in)
+		for i, rule := range in.rules {
+			if out[i].Code != rule {
+				t.Errorf("Expected rule %s, found %v. Input: %s", rule, out[i].Code, in.line)
+			}
 		}
-		if expected_outs[i].Range.End.Character != (*out)[0].Range.End.Character {
-			t.Errorf("Expected end character %d. Recieved %d. Input: %s", expected_outs[i].Range.End.Character, (*out)[0].Range.End.Character, in)
+	}
+}
+
+func TestMissingMemoryOffset(t *testing.T) {
+	tokens := []*[]*parser.Token{parser.TokenizeLine("LDUR X0, [X1]")}
+	out := *Parse(&tokens)
+	if len(out) != 1 || out[0].Message != "Missing immediate operand." || out[0].Range.Start.Character != 12 {
+		t.Errorf("Expected a missing immediate at the bracket, got %v.", out)
+	}
+}
+
+func TestRecoveredOperandRanges(t *testing.T) {
+	inputs := []struct {
+		line  string
+		rules []string
+	}{
+		{"ADDI X0, X1, #99999 junk", []string{RuleTrailingTokens, RuleOutOfRange}},
+		{"ADDI X0 X1, #99999", []string{RuleExpectedComma, RuleOutOfRange}},
+		{"LDUR X0, [X1, #300] junk", []string{RuleTrailingTokens, RuleOutOfRange}},
+		{"MOVZ X0 #70000, LSL #64", []string{RuleExpectedComma, RuleOutOfRange, RuleOutOfRange}},
+		{"ADDI X0, X1, #12 junk", []string{RuleTrailingTokens}},
+	}
+
+	for _, in := range inputs {
+		tokens := []*[]*parser.Token{parser.TokenizeLine(in.line)}
+		out := *Analyze("file:///test.legv8", &tokens, parser.BuildProgram(&tokens))
+		if len(out) != len(in.rules) {
+			t.Errorf("Expected %d diagnostics, found %v. Input: %s", len(in.rules), out, in.line)
+			continue
 		}
-		if expected_outs[i].Range.End.Line != (*out)[0].Range.End.Line {
-			t.Errorf("Expected end line %d. Recieved %d. Input: %s", expected_outs[i].Range.End.Line, (*out)[0].Range.End.Line, in)
+		for i, rule := range in.rules {
+			if out[i].Code != rule {
+				t.Errorf("Expected rule %s, found %v. Input: %s", rule, out[i].Code, in.line)
+			}
 		}
 	}
 }
@@ -0,0 +1,35 @@
+package languageserver
+
+import (
+	"strconv"
+	"strings"
+
+	"server/ast"
+	"server/isa"
+	"server/parser"
+)
+
+// expandPseudo returns the instructions a tokenized pseudo-instruction line is
+// assembled as. The line must already have the shape its syntax expects. A
+// label operand is shown as its address when it is defined in program.
+func expandPseudo(tokens *[]*parser.Token, program *ast.Program) ([]string, bool) {
+	pseudo, ok := isa.PseudoInstructions[(*tokens)[0].Value]
+	if !ok {
+		return nil, false
+	}
+
+	operands := []string{}
+	for _, token := range (*tokens)[1:] {
+		switch token.Type {
+		case parser.RegisterToken, parser.NumberToken:
+			operands = append(operands, strings.TrimPrefix(token.Value, "#"))
+		case parser.LabelToken:
+			if label, ok := program.Label(token.Value); ok {
+				operands = append(operands, strconv.Itoa(label.Index*4))
+			} else {
+				operands = append(operands, token.Value)
+			}
+		}
+	}
+	return pseudo.Expand(operands), true
+}
@@ -0,0 +1,65 @@
+package languageserver
+
+// RegisterClass is the kind of value a register holds.
+type RegisterClass int8
+
+const (
+	IntegerRegister RegisterClass = iota
+	SingleRegister
+	DoubleRegister
+)
+
+func (c RegisterClass) String() string {
+	switch c {
+	case SingleRegister:
+		return "single-precision register"
+	case DoubleRegister:
+		return "double-precision register"
+	}
+	return "integer register"
+}
+
+// operandClasses maps mnemonics that take floating-point registers to the
+// class of each of their register operands, in order. Instructions not listed
+// take only integer registers.
+var operandClasses map[string][]RegisterClass
+
+// registerClass returns the class of a register token's value.
+func registerClass(name string) RegisterClass {
+	if len(name) >= 2 && name[1] >= '0' && name[1] <= '9' {
+		switch name[0] {
+		case 'S':
+			return SingleRegister
+		case 'D':
+			return DoubleRegister
+		}
+	}
+	return IntegerRegister
+}
+
+// expectedRegisterClass returns the class of the n-th register operand of an instruction.
+func expectedRegisterClass(mnemonic string, n int) RegisterClass {
+	classes := operandClasses[mnemonic]
+	if n < len(classes) {
+		return classes[n]
+	}
+	return IntegerRegister
+}
+
+func init() {
+	operandClasses = map[string][]RegisterClass{
+		"LDURS": {SingleRegister, IntegerRegister},
+		"STURS": {SingleRegister, IntegerRegister},
+		"LDURD": {DoubleRegister, IntegerRegister},
+		"STURD": {DoubleRegister, IntegerRegister},
+		"FCMPS": {SingleRegister, SingleRegister},
+		"FCMPD": {DoubleRegister, DoubleRegister},
+	}
+
+	for _, mnemonic := range []string{"FADDS", "FSUBS", "FMULS", "FDIVS"} {
+		operandClasses[mnemonic] = []RegisterClass{SingleRegister, SingleRegister, SingleRegister}
+	}
+	for _, mnemonic := range []string{"FADDD", "FSUBD", "FMULD", "FDIVD"} {
+		operandClasses[mnemonic] = []RegisterClass{DoubleRegister, DoubleRegister, DoubleRegister}
+	}
+}
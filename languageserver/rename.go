@@ -0,0 +1,84 @@
+package languageserver
+
+import (
+	"errors"
+	"fmt"
+
+	lsp "go.lsp.dev/protocol"
+
+	"server/parser"
+)
+
+// PrepareRename returns the range of the label under a position, or an error
+// if the token under the position cannot be renamed.
+func PrepareRename(doc *document, position lsp.Position) (*lsp.Range, error) {
+	token, ok := doc.tokenAt(position)
+	if !ok {
+		return nil, errors.New("Only labels can be renamed.")
+	}
+
+	switch token.Type {
+	case parser.LabelToken:
+		r := tokenRange(int(position.Line), token)
+		return &r, nil
+	case parser.InstructionToken:
+		return nil, fmt.Errorf("%s is an instruction and cannot be renamed.", token.Value)
+	case parser.RegisterToken:
+		return nil, fmt.Errorf("%s is a register and cannot be renamed.", token.Value)
+	}
+	return nil, errors.New("Only labels can be renamed.")
+}
+
+// Rename renames the label under a position, rewriting its definition and
+// every branch that refers to it.
+func Rename(doc *document, position lsp.Position, newName string) (*lsp.WorkspaceEdit, error) {
+	if _, err := PrepareRename(doc, position); err != nil {
+		return nil, err
+	}
+	token, _ := doc.tokenAt(position)
+	if token.Value == newName {
+		return &lsp.WorkspaceEdit{}, nil
+	}
+	if err := validateLabelName(doc, newName); err != nil {
+		return nil, err
+	}
+
+	edits := []lsp.TextEdit{}
+	program := doc.Program()
+	for _, definition := range program.Labels() {
+		if definition.Name == token.Value {
+			edits = append(edits, lsp.TextEdit{Range: lspRange(definition.Range), NewText: newName})
+		}
+	}
+	for _, reference := range program.LabelRefs(token.Value) {
+		edits = append(edits, lsp.TextEdit{Range: lspRange(reference.Range), NewText: newName})
+	}
+
+	return &lsp.WorkspaceEdit{
+		Changes: map[lsp.DocumentURI][]lsp.TextEdit{
+			doc.uri: edits,
+		},
+	}, nil
+}
+
+// validateLabelName checks that name is a legal label that does not collide
+// with a mnemonic or a label already defined in the document.
+func validateLabelName(doc *document, name string) error {
+	if name == "" || parser.Identifier(name, 0) != name {
+		return fmt.Errorf("%q is not a valid label. Labels start with a letter followed by letters, digits or underscores.", name)
+	}
+	if _, ok := parser.KeywordInstructionTypes[name]; ok {
+		return fmt.Errorf("%s is an instruction and cannot be used as a label.", name)
+	}
+
+	// the name must read back as a label, not as a register or instruction prefix
+	tokens := *parser.TokenizeLine(name + ":")
+	if len(tokens) != 2 || tokens[0].Type != parser.LabelToken || tokens[0].Value != name {
+		return fmt.Errorf("%s cannot be used as a label because it is read as a %s.", name, tokens[0].Type.String())
+	}
+
+	if definition, ok := doc.Program().Label(name); ok {
+		return fmt.Errorf("Label %s is already defined on line %d.", name, definition.Range.Start.Line+1)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package languageserver
+
+import (
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestPrepareRename(t *testing.T) {
+	doc := newDocument(uri.File("/tmp/test.legv8"), "loop:\nCBZ X1, loop")
+
+	if r, err := PrepareRename(doc, lsp.Position{Line: 1, Character: 9}); err != nil || r.Start.Character != 8 || r.End.Character != 12 {
+		t.Errorf("Expected label range, got %v (err=%v).", r, err)
+	}
+	if _, err := PrepareRename(doc, lsp.Position{Line: 1, Character: 1}); err == nil {
+		t.Errorf("Expected error renaming a mnemonic.")
+	}
+	if _, err := PrepareRename(doc, lsp.Position{Line: 1, Character: 4}); err == nil {
+		t.Errorf("Expected error renaming a register.")
+	}
+}
+
+func TestRename(t *testing.T) {
+	doc := newDocument(uri.File("/tmp/test.legv8"), "loop:\nCBZ X1, loop\nB loop\nend:")
+
+	edit, err := Rename(doc, lsp.Position{Line: 0, Character: 0}, "top")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	edits := edit.Changes[doc.uri]
+	if len(edits) != 3 {
+		t.Fatalf("Expected 3 edits, got %d.", len(edits))
+	}
+	for _, e := range edits {
+		if e.NewText != "top" {
+			t.Errorf("Expected new text 'top', got %q.", e.NewText)
+		}
+	}
+
+	invalid := []string{"", "1abc", "_abc", "a-b", "ADD", "B", "X1", "XZR", "end"}
+	for _, name := range invalid {
+		if _, err := Rename(doc, lsp.Position{Line: 0, Character: 0}, name); err == nil {
+			t.Errorf("Expected error renaming to %q.", name)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package languageserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// Diagnostic codes are stable across releases so configuration, tests and
+// tools can refer to them. E codes are errors and W codes warnings by
+// default; the tens digit groups them into syntax (0), labels (1) and
+// operand encoding (2).
+const (
+	RuleExpectedComma       = "LEGV8-E001"
+	RuleMissingOperand      = "LEGV8-E002"
+	RuleOperandType         = "LEGV8-E003"
+	RuleUnbalancedBrackets  = "LEGV8-E004"
+	RuleTrailingTokens      = "LEGV8-E005"
+	RuleExpectedInstruction = "LEGV8-E006"
+	RuleDuplicateLabel      = "LEGV8-E010"
+	RuleUndefinedLabel      = "LEGV8-E011"
+	RuleReservedLabel       = "LEGV8-E012"
+	RuleUnusedLabel         = "LEGV8-W010"
+	RuleRegisterClass       = "LEGV8-E020"
+	RuleOutOfRange          = "LEGV8-E021"
+	RuleUnknownRegister     = "LEGV8-E022"
+)
+
+// Rule describes a kind of problem the server reports.
+type Rule struct {
+	Code        string
+	Name        string
+	Severity    lsp.DiagnosticSeverity
+	Description string
+}
+
+// Rules is the catalogue of every rule, grouped as the codes are.
+var Rules = []*Rule{
+	{RuleExpectedComma, "expected-comma", lsp.DiagnosticSeverityError, "Operands must be separated by commas."},
+	{RuleMissingOperand, "missing-operand", lsp.DiagnosticSeverityError, "An instruction is missing one of its operands."},
+	{RuleOperandType, "operand-type", lsp.DiagnosticSeverityError, "An operand is of the wrong kind, such as an immediate where a register belongs."},
+	{RuleUnbalancedBrackets, "unbalanced-brackets", lsp.DiagnosticSeverityError, "A memory operand is missing a bracket, or has one where none belongs."},
+	{RuleTrailingTokens, "trailing-tokens", lsp.DiagnosticSeverityError, "A line continues after its instruction's last operand."},
+	{RuleExpectedInstruction, "expected-instruction", lsp.DiagnosticSeverityError, "A line that is not a label does not start with an instruction."},
+	{RuleDuplicateLabel, "duplicate-label", lsp.DiagnosticSeverityError, "A label is defined more than once."},
+	{RuleUndefinedLabel, "undefined-label", lsp.DiagnosticSeverityError, "A branch refers to a label that is not defined."},
+	{RuleReservedLabel, "reserved-label", lsp.DiagnosticSeverityError, "A label is named after an instruction."},
+	{RuleUnusedLabel, "unused-label", lsp.DiagnosticSeverityWarning, "A label is defined but no instruction refers to it."},
+	{RuleRegisterClass, "register-class", lsp.DiagnosticSeverityError, "A register operand is of the wrong class, such as an integer register in a floating-point instruction."},
+	{RuleOutOfRange, "out-of-range", lsp.DiagnosticSeverityError, "An immediate or branch offset does not fit in the field it is encoded in."},
+	{RuleUnknownRegister, "unknown-register", lsp.DiagnosticSeverityError, "A register that LEGv8 does not have, such as X32, is used."},
+}
+
+// LookupRule finds a rule by its code or name.
+func LookupRule(id string) (*Rule, bool) {
+	for _, rule := range Rules {
+		if strings.EqualFold(rule.Code, id) || rule.Name == id {
+			return rule, true
+		}
+	}
+	return nil, false
+}
+
+// severities are the values a rule can be configured with, besides off.
+var severities = map[string]lsp.DiagnosticSeverity{
+	"error":       lsp.DiagnosticSeverityError,
+	"warning":     lsp.DiagnosticSeverityWarning,
+	"information": lsp.DiagnosticSeverityInformation,
+	"hint":        lsp.DiagnosticSeverityHint,
+}
+
+// RuleSettings configures which rules are reported and how.
+type RuleSettings struct {
+	// Rules maps a rule code or name to "off", to disable it, or to the
+	// severity to report it with: "error", "warning", "information" or
+	// "hint". Rules not listed keep their default severity.
+	Rules map[string]string `json:"rules,omitempty"`
+
+	// DocumentationURL is the page describing the rules. When set, each
+	// diagnostic links to it, with the lowercased code as the fragment.
+	DocumentationURL string `json:"documentationUrl,omitempty"`
+}
+
+// Validate reports unknown rules and severities.
+func (s *RuleSettings) Validate() error {
+	ids := make([]string, 0, len(s.Rules))
+	for id := range s.Rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if _, ok := LookupRule(id); !ok {
+			return fmt.Errorf("unknown rule %s", id)
+		}
+		if level := s.Rules[id]; level != "off" {
+			if _, ok := severities[level]; !ok {
+				return fmt.Errorf("unknown severity %s for rule %s", level, id)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply removes diagnostics of disabled rules, sets the configured severity
+// of the rest and links each to its documentation.
+func (s *RuleSettings) Apply(diagnostics []lsp.Diagnostic) []lsp.Diagnostic {
+	levels := map[string]string{}
+	for id, level := range s.Rules {
+		if rule, ok := LookupRule(id); ok {
+			levels[rule.Code] = level
+		}
+	}
+
+	applied := []lsp.Diagnostic{}
+	for _, diagnostic := range diagnostics {
+		code, _ := diagnostic.Code.(string)
+		level, ok := levels[code]
+		if level == "off" {
+			continue
+		}
+		if ok {
+			diagnostic.Severity = severities[level]
+		}
+		if s.DocumentationURL != "" && code != "" {
+			diagnostic.CodeDescription = &lsp.CodeDescription{Href: uri.URI(s.DocumentationURL + "#" + strings.ToLower(code))}
+		}
+		applied = append(applied, diagnostic)
+	}
+	return applied
+}
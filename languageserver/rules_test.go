@@ -0,0 +1,106 @@
+package languageserver
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestRuleCatalogue(t *testing.T) {
+	codes, names := map[string]bool{}, map[string]bool{}
+	for _, rule := range Rules {
+		if codes[rule.Code] || names[rule.Name] {
+			t.Errorf("Rule %s (%s) is listed twice.", rule.Code, rule.Name)
+		}
+		codes[rule.Code], names[rule.Name] = true, true
+
+		if byCode, ok := LookupRule(strings.ToLower(rule.Code)); !ok || byCode != rule {
+			t.Errorf("Cannot look up rule %s by code.", rule.Code)
+		}
+		if byName, ok := LookupRule(rule.Name); !ok || byName != rule {
+			t.Errorf("Cannot look up rule %s by name.", rule.Name)
+		}
+	}
+}
+
+// TestRuleDocumentation checks that the examples in docs/rules.md report the
+// rule they document, and that the corrected examples report nothing.
+func TestRuleDocumentation(t *testing.T) {
+	text, err := ioutil.ReadFile("../docs/rules.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := uri.File("/tmp/test.legv8")
+	sections := strings.Split(string(text), "\n## ")[1:]
+	if len(sections) != len(Rules) {
+		t.Errorf("Expected %d rules documented, found %d.", len(Rules), len(sections))
+	}
+	for _, section := range sections {
+		code := strings.SplitN(section, "\n", 2)[0]
+		if _, ok := LookupRule(code); !ok {
+			t.Errorf("Documented rule %s is not in the catalogue.", code)
+			continue
+		}
+
+		examples := strings.Split(section, "```legv8\n")[1:]
+		if len(examples) != 2 {
+			t.Errorf("Expected an example and a correction for %s.", code)
+			continue
+		}
+		bad := strings.SplitN(examples[0], "\n```", 2)[0]
+		good := strings.SplitN(examples[1], "\n```", 2)[0]
+
+		found := false
+		doc := newDocument(u, bad)
+		for _, d := range *Analyze(u, doc.tokens, doc.Program()) {
+			found = found || d.Code == code
+		}
+		if !found {
+			t.Errorf("Example for %s does not report it: %q", code, bad)
+		}
+		doc = newDocument(u, good)
+		if out := *Analyze(u, doc.tokens, doc.Program()); len(out) != 0 {
+			t.Errorf("Correction for %s reports %v: %q", code, out, good)
+		}
+	}
+}
+
+func TestRuleSettings(t *testing.T) {
+	diagnostics := []lsp.Diagnostic{
+		{Code: RuleExpectedComma, Severity: lsp.DiagnosticSeverityError},
+		{Code: RuleUnusedLabel, Severity: lsp.DiagnosticSeverityWarning},
+		{Code: RuleOutOfRange, Severity: lsp.DiagnosticSeverityError},
+	}
+	settings := RuleSettings{
+		Rules:            map[string]string{"unused-label": "off", "LEGV8-E021": "hint"},
+		DocumentationURL: "https://example.com/rules",
+	}
+	if err := settings.Validate(); err != nil {
+		t.Fatalf("Unexpected error %v.", err)
+	}
+
+	out := settings.Apply(diagnostics)
+	if len(out) != 2 || out[0].Code != RuleExpectedComma || out[1].Code != RuleOutOfRange {
+		t.Fatalf("Expected %s and %s, got %v.", RuleExpectedComma, RuleOutOfRange, out)
+	}
+	if out[0].Severity != lsp.DiagnosticSeverityError || out[1].Severity != lsp.DiagnosticSeverityHint {
+		t.Errorf("Incorrect severities %v and %v.", out[0].Severity, out[1].Severity)
+	}
+	if out[1].CodeDescription == nil || out[1].CodeDescription.Href != "https://example.com/rules#legv8-e021" {
+		t.Errorf("Incorrect code description %v.", out[1].CodeDescription)
+	}
+
+	invalid := []RuleSettings{
+		{Rules: map[string]string{"LEGV8-E999": "off"}},
+		{Rules: map[string]string{"expected-comma": "fatal"}},
+	}
+	for _, settings := range invalid {
+		if err := settings.Validate(); err == nil {
+			t.Errorf("Expected an error for %v.", settings.Rules)
+		}
+	}
+}
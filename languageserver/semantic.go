@@ -0,0 +1,146 @@
+package languageserver
+
+import (
+	"fmt"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+
+	"server/assembler"
+	"server/ast"
+	"server/parser"
+)
+
+// Analyze checks the shape of every line with Parse, then checks the file as a
+// whole for problems that span lines, such as branches to undefined labels,
+// and for immediates that do not fit their encoding. The program is the
+// syntax tree built from tokens.
+func Analyze(u uri.URI, tokens *[]*[]*parser.Token, program *ast.Program) *[]lsp.Diagnostic {
+	diagnostics := Parse(tokens)
+	*diagnostics = append(*diagnostics, checkReservedLabels(tokens)...)
+	*diagnostics = append(*diagnostics, checkLabels(u, program)...)
+	*diagnostics = append(*diagnostics, checkEncoding(program)...)
+	return diagnostics
+}
+
+// checkReservedLabels reports label definitions named after instructions,
+// which the tokenizer reads as instructions rather than labels.
+func checkReservedLabels(lines *[]*[]*parser.Token) []lsp.Diagnostic {
+	diagnostics := []lsp.Diagnostic{}
+	for i, tokens := range *lines {
+		if len(*tokens) < 2 || (*tokens)[0].Type != parser.InstructionToken || (*tokens)[1].Type != parser.ColonToken {
+			continue
+		}
+		label := (*tokens)[0]
+		diagnostics = append(diagnostics, lsp.Diagnostic{
+			Range:    tokenRange(i, label),
+			Severity: lsp.DiagnosticSeverityError,
+			Code:     RuleReservedLabel,
+			Message:  fmt.Sprintf("'%s' is an instruction and cannot be used as a label.", label.Value),
+			Source:   "compiler",
+		})
+	}
+	return diagnostics
+}
+
+// checkLabels reports duplicate label definitions, labels that are never
+// used and branches to labels that are never defined. Labels at the start of
+// the program are entry points and are not reported as unused.
+func checkLabels(u uri.URI, program *ast.Program) []lsp.Diagnostic {
+	diagnostics := []lsp.Diagnostic{}
+	definitions := map[string]*ast.LabelDef{}
+
+	for _, label := range program.Labels() {
+		first, ok := definitions[label.Name]
+		if !ok {
+			definitions[label.Name] = label
+			continue
+		}
+		diagnostics = append(diagnostics, lsp.Diagnostic{
+			Range:    lspRange(label.Range),
+			Severity: lsp.DiagnosticSeverityError,
+			Code:     RuleDuplicateLabel,
+			Message:  fmt.Sprintf("Label '%s' is already defined on line %d.", label.Name, first.Range.Start.Line+1),
+			Source:   "compiler",
+			RelatedInformation: []lsp.DiagnosticRelatedInformation{
+				{
+					Location: lsp.Location{URI: u, Range: lspRange(first.Range)},
+					Message:  "First definition of " + label.Name,
+				},
+			},
+		})
+	}
+
+	// count references once rather than scanning the program for each label
+	refs := map[string]int{}
+	for _, instruction := range program.Instructions() {
+		for _, operand := range instruction.Operands {
+			if ref, ok := operand.(*ast.LabelRef); ok {
+				refs[ref.Name]++
+			}
+		}
+	}
+
+	for _, label := range program.Labels() {
+		// labels before the first instruction mark the entry point, such as main
+		if definitions[label.Name] != label || refs[label.Name] > 0 || label.Index == 0 {
+			continue
+		}
+		diagnostics = append(diagnostics, lsp.Diagnostic{
+			Range:    lspRange(label.Range),
+			Severity: lsp.DiagnosticSeverityWarning,
+			Code:     RuleUnusedLabel,
+			Message:  fmt.Sprintf("Label '%s' is never used.", label.Name),
+			Source:   "compiler",
+		})
+	}
+
+	for _, instruction := range program.Instructions() {
+		// only branches and ADR take labels; labels elsewhere are shape errors
+		format := parser.KeywordInstructionTypes[instruction.Mnemonic]
+		if format != parser.B && format != parser.CB && instruction.Mnemonic != "ADR" {
+			continue
+		}
+		for _, operand := range instruction.Operands {
+			ref, ok := operand.(*ast.LabelRef)
+			if !ok {
+				continue
+			}
+			if _, ok := definitions[ref.Name]; ok {
+				continue
+			}
+			diagnostics = append(diagnostics, lsp.Diagnostic{
+				Range:    lspRange(ref.Range),
+				Severity: lsp.DiagnosticSeverityError,
+				Code:     RuleUndefinedLabel,
+				Message:  fmt.Sprintf("Label '%s' is not defined.", ref.Name),
+				Source:   "compiler",
+			})
+		}
+	}
+
+	return diagnostics
+}
+
+// checkEncoding reports immediates and branch offsets that do not fit in the
+// field their instruction encodes them in, as found by the assembler.
+func checkEncoding(program *ast.Program) []lsp.Diagnostic {
+	diagnostics := []lsp.Diagnostic{}
+
+	// malformed instructions and undefined labels are reported by the other checks
+	_, errs := assembler.Assemble(program)
+	for _, err := range errs {
+		if err.Kind != assembler.RangeError {
+			continue
+		}
+		diagnostics = append(diagnostics, lsp.Diagnostic{
+			Range:    lspRange(err.Range),
+			Severity: lsp.DiagnosticSeverityError,
+			Code:     RuleOutOfRange,
+			Message:  err.Message,
+			Source:   "compiler",
+		})
+	}
+
+	return diagnostics
+}
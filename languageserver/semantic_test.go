@@ -0,0 +1,67 @@
+package languageserver
+
+import (
+	"strings"
+	"testing"
+
+	"go.lsp.dev/uri"
+)
+
+func TestAnalyze(t *testing.T) {
+	inputs := []struct {
+		text  string
+		codes []string
+	}{
+		{"loop:\nB loop\nCBZ X1, loop", nil},
+		{"main:\nloop:\nB loop", nil},
+		{"main:\nHALT\nskip:\nHALT", []string{RuleUnusedLabel}},
+		{"B done", []string{RuleUndefinedLabel}},
+		{"CBZ X1, done\nB.EQ done\ndone:", nil},
+		{"loop:\nHALT\nloop:", []string{RuleDuplicateLabel}},
+		{"HALT\nloop:\nHALT\nloop:", []string{RuleDuplicateLabel, RuleUnusedLabel}},
+		{"ADD:\nB:", []string{RuleReservedLabel, RuleReservedLabel}},
+		{"ADD X1, X2, done", []string{RuleOperandType}},
+		{"ADDI X0, X0, #4095\nSUBI X0, X0, #0", nil},
+		{"ADDI X0, X0, #5000", []string{RuleOutOfRange}},
+		{"LSL X0, X0, #63\nLSR X0, X0, #64", []string{RuleOutOfRange}},
+		{"LDUR X0, [SP, #-256]\nSTUR X0, [SP, #255]", nil},
+		{"LDUR X0, [SP, #-257]\nSTUR X0, [SP, #256]", []string{RuleOutOfRange, RuleOutOfRange}},
+		{"ADDI X0, X0, #-1", []string{RuleOutOfRange}},
+		{"MOVZ X1, #65535, LSL #48\nMOVK X1, #0, LSL #0", nil},
+		{"MOVZ X1, #65536, LSL #8", []string{RuleOutOfRange, RuleOutOfRange}},
+		{"MOV X1, X2\nCMP X1, X2\nCMPI X1, #4095\nLDA X1, [SP, #16]", nil},
+		{"CMPI X1, #4096", []string{RuleOutOfRange}},
+		{"MOVZ X1, #1\nMOV X1, #2", []string{RuleExpectedComma, RuleOperandType}},
+		{"ADR X1, data\nHALT\ndata:", nil},
+		{"ADR X1, nowhere", []string{RuleUndefinedLabel}},
+		{"ADR X1, #4", []string{RuleOperandType}},
+		{"MNEG X1, X2, X3\nSMULL X1, X2, X3\nUMULL X1, X2, X3", nil},
+		{"MNEG X1, X2", []string{RuleExpectedComma}},
+		{"S1:\nB S1\nD2:\nCBZ X1, D2", nil},
+		{"FADDS S1, S2, S3\nLDURD D2, [X1, #8]", nil},
+	}
+
+	u := uri.File("/tmp/test.legv8")
+	for _, in := range inputs {
+		doc := newDocument(u, in.text)
+		out := *Analyze(u, doc.tokens, doc.Program())
+
+		if len(out) != len(in.codes) {
+			t.Errorf("Expected %d diagnostics, got %d. Input: %q. Out = %v", len(in.codes), len(out), in.text, out)
+			continue
+		}
+		for i, code := range in.codes {
+			if out[i].Code != code {
+				t.Errorf("Expected code %s, got %v. Input: %q", code, out[i].Code, in.text)
+			}
+		}
+	}
+
+	doc := newDocument(u, "loop:\nHALT\nloop:")
+	out := *Analyze(u, doc.tokens, doc.Program())
+	if len(out) != 1 || len(out[0].RelatedInformation) != 1 || out[0].RelatedInformation[0].Location.Range.Start.Line != 0 {
+		t.Errorf("Expected duplicate label to point at first definition. Out = %v", out)
+	} else if !strings.Contains(out[0].RelatedInformation[0].Message, "loop") {
+		t.Errorf("Unexpected related information message %q.", out[0].RelatedInformation[0].Message)
+	}
+}
@@ -0,0 +1,270 @@
+package languageserver
+
+import (
+	"strconv"
+	"strings"
+
+	lsp "go.lsp.dev/protocol"
+
+	"server/parser"
+)
+
+// Semantic token types, in the order of semanticTokenTypes.
+const (
+	semanticKeyword = iota
+	semanticRegister
+	semanticNumber
+	semanticLabel
+	semanticComment
+	semanticOperator
+)
+
+var semanticTokenTypes = []lsp.SemanticTokenTypes{
+	lsp.SemanticTokenKeyword,
+	lsp.SemanticTokenVariable,
+	lsp.SemanticTokenNumber,
+	"label",
+	lsp.SemanticTokenComment,
+	lsp.SemanticTokenOperator,
+}
+
+// Semantic token modifiers, as bits in the order of semanticTokenModifiers.
+// Instructions carry the modifier of their format and special registers the
+// modifier of their role.
+const (
+	modifierDeclaration = 1 << iota
+	modifierReadonly
+	modifierRFormat
+	modifierIFormat
+	modifierDFormat
+	modifierBFormat
+	modifierCBFormat
+	modifierIWFormat
+	modifierPseudo
+	modifierSimulator
+	modifierStackPointer
+	modifierFramePointer
+	modifierLinkRegister
+	modifierZeroRegister
+)
+
+var semanticTokenModifiers = []lsp.SemanticTokenModifiers{
+	lsp.SemanticTokenModifierDeclaration,
+	lsp.SemanticTokenModifierReadonly,
+	"rFormat",
+	"iFormat",
+	"dFormat",
+	"bFormat",
+	"cbFormat",
+	"iwFormat",
+	"pseudo",
+	"simulator",
+	"stackPointer",
+	"framePointer",
+	"linkRegister",
+	"zeroRegister",
+}
+
+// formatModifiers maps each instruction type to its modifier. BR is encoded
+// in the R format and PRNT, like the other simulator instructions, in no
+// format students need to know.
+var formatModifiers = map[parser.InstructionType]uint32{
+	parser.R:      modifierRFormat,
+	parser.BR:     modifierRFormat,
+	parser.I:      modifierIFormat,
+	parser.D:      modifierDFormat,
+	parser.B:      modifierBFormat,
+	parser.CB:     modifierCBFormat,
+	parser.IW:     modifierIWFormat,
+	parser.PSEUDO: modifierPseudo,
+	parser.IM:     modifierSimulator,
+	parser.IGNORE: modifierSimulator,
+}
+
+// registerModifiers maps special registers, by any of their names, to their
+// modifiers.
+var registerModifiers = map[string]uint32{
+	"SP":  modifierStackPointer,
+	"X28": modifierStackPointer,
+	"FP":  modifierFramePointer,
+	"X29": modifierFramePointer,
+	"LR":  modifierLinkRegister,
+	"X30": modifierLinkRegister,
+	"XZR": modifierZeroRegister | modifierReadonly,
+}
+
+// semanticTokensOptions is the semanticTokensProvider capability, which
+// lsp.SemanticTokensOptions does not describe in full.
+type semanticTokensOptions struct {
+	Legend lsp.SemanticTokensLegend  `json:"legend"`
+	Range  bool                      `json:"range"`
+	Full   semanticTokensFullOptions `json:"full"`
+}
+
+type semanticTokensFullOptions struct {
+	Delta bool `json:"delta"`
+}
+
+// semanticTokensLegend describes the token types and modifiers to the client.
+func semanticTokensLegend() lsp.SemanticTokensLegend {
+	return lsp.SemanticTokensLegend{
+		TokenTypes:     semanticTokenTypes,
+		TokenModifiers: semanticTokenModifiers,
+	}
+}
+
+// semanticToken is a token in absolute coordinates, before encoding.
+type semanticToken struct {
+	line, start, length uint32
+	tokenType           uint32
+	modifiers           uint32
+}
+
+// classify returns the semantic token for a token of a line, if it has one.
+// Punctuation and unknown characters are left to the client.
+func classify(line int, tokens *[]*parser.Token, i int) (semanticToken, bool) {
+	token := (*tokens)[i]
+	t := semanticToken{line: uint32(line), start: uint32(token.Start), length: uint32(token.End - token.Start)}
+
+	switch token.Type {
+	case parser.InstructionToken:
+		// an instruction name before a colon is parsed as a label
+		if i+1 < len(*tokens) && (*tokens)[i+1].Type == parser.ColonToken {
+			t.tokenType, t.modifiers = semanticLabel, modifierDeclaration
+			return t, true
+		}
+		t.tokenType, t.modifiers = semanticKeyword, formatModifiers[token.InstructionType]
+	case parser.LabelToken:
+		t.tokenType = semanticLabel
+		if i+1 < len(*tokens) && (*tokens)[i+1].Type == parser.ColonToken {
+			t.modifiers = modifierDeclaration
+		}
+	case parser.RegisterToken:
+		t.tokenType, t.modifiers = semanticRegister, registerModifiers[token.Value]
+	case parser.NumberToken:
+		t.tokenType = semanticNumber
+	case parser.ShiftToken:
+		t.tokenType = semanticOperator
+	default:
+		return t, false
+	}
+	return t, true
+}
+
+// commentStart returns the offset of the comment on a line, if any. Anything
+// but whitespace after the last token would have been tokenized, so a comment
+// can only follow it.
+func commentStart(line string, tokens *[]*parser.Token) (int, bool) {
+	start := 0
+	if len(*tokens) > 0 {
+		start = (*tokens)[len(*tokens)-1].End
+	}
+	if start > len(line) {
+		return 0, false
+	}
+	i := strings.Index(line[start:], "//")
+	if i < 0 {
+		return 0, false
+	}
+	return start + i, true
+}
+
+// semanticTokens returns the semantic tokens of the lines of a document
+// from startLine up to but not including endLine.
+func semanticTokens(doc *document, startLine, endLine int) []semanticToken {
+	result := []semanticToken{}
+	for i := startLine; i < endLine && i < len(*doc.tokens); i++ {
+		tokens := (*doc.tokens)[i]
+		for j := range *tokens {
+			if t, ok := classify(i, tokens, j); ok {
+				result = append(result, t)
+			}
+		}
+		if start, ok := commentStart(doc.lines[i], tokens); ok {
+			// comments may hold any text, so measure them in UTF-16 code units
+			line := doc.lines[i]
+			result = append(result, semanticToken{uint32(i), uint32(utf16Length(line[:start])), uint32(utf16Length(line[start:])), semanticComment, 0})
+		}
+	}
+	return result
+}
+
+// encodeSemanticTokens encodes tokens relative to each previous token, as
+// five integers each.
+func encodeSemanticTokens(tokens []semanticToken) []uint32 {
+	data := make([]uint32, 0, 5*len(tokens))
+	var line, start uint32
+	for _, t := range tokens {
+		if t.line != line {
+			start = 0
+		}
+		data = append(data, t.line-line, t.start-start, t.length, t.tokenType, t.modifiers)
+		line, start = t.line, t.start
+	}
+	return data
+}
+
+// SemanticTokens returns the encoded semantic tokens of a whole document.
+func SemanticTokens(doc *document) []uint32 {
+	return encodeSemanticTokens(semanticTokens(doc, 0, len(doc.lines)))
+}
+
+// SemanticTokensRange returns the encoded semantic tokens that overlap r.
+func SemanticTokensRange(doc *document, r lsp.Range) []uint32 {
+	// tokens are measured in UTF-16 code units, as the range is
+	startLine, startByte := doc.offset(r.Start)
+	endLine, endByte := doc.offset(r.End)
+	startChar := utf16Length(doc.lines[startLine][:startByte])
+	endChar := utf16Length(doc.lines[endLine][:endByte])
+
+	tokens := []semanticToken{}
+	for _, t := range semanticTokens(doc, startLine, endLine+1) {
+		if int(t.line) == startLine && int(t.start+t.length) <= startChar {
+			continue
+		}
+		if int(t.line) == endLine && int(t.start) >= endChar {
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	return encodeSemanticTokens(tokens)
+}
+
+// semanticTokensEdit returns the single edit that turns previous into
+// current, replacing everything between their common prefix and suffix.
+func semanticTokensEdit(previous, current []uint32) lsp.SemanticTokensEdit {
+	prefix := 0
+	for prefix < len(previous) && prefix < len(current) && previous[prefix] == current[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(previous)-prefix && suffix < len(current)-prefix &&
+		previous[len(previous)-1-suffix] == current[len(current)-1-suffix] {
+		suffix++
+	}
+
+	return lsp.SemanticTokensEdit{
+		Start:       uint32(prefix),
+		DeleteCount: uint32(len(previous) - prefix - suffix),
+		Data:        current[prefix : len(current)-suffix],
+	}
+}
+
+// semanticTokensResult is the last full result sent for a document, kept to
+// answer delta requests.
+type semanticTokensResult struct {
+	id   string
+	data []uint32
+}
+
+// nextSemanticResult records data as the latest result for a document and
+// returns its ID.
+func (d *document) nextSemanticResult(data []uint32) string {
+	id := "1"
+	if d.semantic != nil {
+		previous, _ := strconv.Atoi(d.semantic.id)
+		id = strconv.Itoa(previous + 1)
+	}
+	d.semantic = &semanticTokensResult{id, data}
+	return id
+}
@@ -0,0 +1,151 @@
+package languageserver
+
+import (
+	"reflect"
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestSemanticTokens(t *testing.T) {
+	doc := newDocument(uri.File("/tmp/test.legv8"), "loop:\n    ADDI SP, SP, #16 // grow\nB loop\n// done")
+
+	expected := []uint32{
+		0, 0, 4, semanticLabel, modifierDeclaration,
+		1, 4, 4, semanticKeyword, modifierIFormat,
+		0, 5, 2, semanticRegister, modifierStackPointer,
+		0, 4, 2, semanticRegister, modifierStackPointer,
+		0, 4, 3, semanticNumber, 0,
+		0, 4, 7, semanticComment, 0,
+		1, 0, 1, semanticKeyword, modifierBFormat,
+		0, 2, 4, semanticLabel, 0,
+		1, 0, 7, semanticComment, 0,
+	}
+	if data := SemanticTokens(doc); !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected tokens %v, got %v.", expected, data)
+	}
+}
+
+func TestSemanticTokensUnicodeComment(t *testing.T) {
+	doc := newDocument(uri.File("/tmp/test.legv8"), "HALT // café 🎉\n// ✓ ok")
+
+	expected := []uint32{
+		0, 0, 4, semanticKeyword, modifierSimulator,
+		0, 5, 10, semanticComment, 0,
+		1, 0, 7, semanticComment, 0,
+	}
+	if data := SemanticTokens(doc); !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected tokens %v, got %v.", expected, data)
+	}
+}
+
+func TestSemanticTokenModifiers(t *testing.T) {
+	tests := []struct {
+		input     string
+		tokenType uint32
+		modifiers uint32
+	}{
+		{"CBZ", semanticKeyword, modifierCBFormat},
+		{"MOVZ", semanticKeyword, modifierIWFormat},
+		{"BR", semanticKeyword, modifierRFormat},
+		{"MOV", semanticKeyword, modifierPseudo},
+		{"HALT", semanticKeyword, modifierSimulator},
+		{"FP", semanticRegister, modifierFramePointer},
+		{"X30", semanticRegister, modifierLinkRegister},
+		{"XZR", semanticRegister, modifierZeroRegister | modifierReadonly},
+		{"X9", semanticRegister, 0},
+		{"ADD:", semanticLabel, modifierDeclaration},
+	}
+
+	for _, test := range tests {
+		doc := newDocument(uri.File("/tmp/test.legv8"), test.input)
+		data := SemanticTokens(doc)
+		if len(data) < 5 || data[3] != test.tokenType || data[4] != test.modifiers {
+			t.Errorf("Expected type %d with modifiers %b, got %v. Input: %q", test.tokenType, test.modifiers, data, test.input)
+		}
+	}
+}
+
+func TestSemanticTokensRange(t *testing.T) {
+	doc := newDocument(uri.File("/tmp/test.legv8"), "ADD X1, X2, X3\nSUB X4, X5, X6\nB done")
+
+	data := SemanticTokensRange(doc, lsp.Range{
+		Start: lsp.Position{Line: 1, Character: 5},
+		End:   lsp.Position{Line: 2, Character: 1},
+	})
+	expected := []uint32{
+		1, 4, 2, semanticRegister, 0,
+		0, 4, 2, semanticRegister, 0,
+		0, 4, 2, semanticRegister, 0,
+		1, 0, 1, semanticKeyword, modifierBFormat,
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected tokens %v, got %v.", expected, data)
+	}
+}
+
+func TestSemanticTokensRangeUnicode(t *testing.T) {
+	tests := []struct {
+		text    string
+		r       lsp.Range
+		comment bool
+	}{
+		// the comment ends at character 14 but byte 21
+		{"HALT // 🎉🎉🎉\nHALT", lsp.Range{Start: lsp.Position{Line: 0, Character: 12}, End: lsp.Position{Line: 1, Character: 4}}, true},
+		{"HALT // 🎉🎉🎉\nHALT", lsp.Range{Start: lsp.Position{Line: 0, Character: 14}, End: lsp.Position{Line: 1, Character: 4}}, false},
+		// the comment starts at character 4 but byte 5
+		{"B é // done", lsp.Range{End: lsp.Position{Line: 0, Character: 4}}, false},
+		{"B é // done", lsp.Range{End: lsp.Position{Line: 0, Character: 5}}, true},
+	}
+
+	for _, test := range tests {
+		doc := newDocument(uri.File("/tmp/test.legv8"), test.text)
+		data := SemanticTokensRange(doc, test.r)
+		comment := false
+		for i := 3; i < len(data); i += 5 {
+			comment = comment || data[i] == semanticComment
+		}
+		if comment != test.comment {
+			t.Errorf("Expected comment in range to be %t, got %v. Input: %q %v", test.comment, data, test.text, test.r)
+		}
+	}
+}
+
+func TestSemanticTokensEdit(t *testing.T) {
+	tests := []struct {
+		previous []uint32
+		current  []uint32
+		expected lsp.SemanticTokensEdit
+	}{
+		{[]uint32{1, 2, 3}, []uint32{1, 2, 3}, lsp.SemanticTokensEdit{Start: 3, DeleteCount: 0, Data: []uint32{}}},
+		{[]uint32{1, 2, 3}, []uint32{1, 9, 3}, lsp.SemanticTokensEdit{Start: 1, DeleteCount: 1, Data: []uint32{9}}},
+		{[]uint32{1, 2, 3}, []uint32{1, 2, 3, 4, 5}, lsp.SemanticTokensEdit{Start: 3, DeleteCount: 0, Data: []uint32{4, 5}}},
+		{[]uint32{1, 2, 2, 3}, []uint32{1, 2, 3}, lsp.SemanticTokensEdit{Start: 2, DeleteCount: 1, Data: []uint32{}}},
+	}
+
+	for _, test := range tests {
+		edit := semanticTokensEdit(test.previous, test.current)
+		if !reflect.DeepEqual(edit, test.expected) {
+			t.Errorf("Expected edit %v, got %v. Input: %v -> %v", test.expected, edit, test.previous, test.current)
+		}
+	}
+}
+
+func TestSemanticTokensDelta(t *testing.T) {
+	doc := newDocument(uri.File("/tmp/test.legv8"), "ADD X1, X2, X3")
+	previous := SemanticTokens(doc)
+	id := doc.nextSemanticResult(previous)
+
+	doc.applyChange(lsp.Range{Start: lsp.Position{Line: 0, Character: 12}, End: lsp.Position{Line: 0, Character: 14}}, "XZR")
+	current := SemanticTokens(doc)
+	if next := doc.nextSemanticResult(current); next == id {
+		t.Errorf("Expected a new result ID, got %q again.", next)
+	}
+
+	edit := semanticTokensEdit(previous, current)
+	expected := lsp.SemanticTokensEdit{Start: 17, DeleteCount: 3, Data: []uint32{3, semanticRegister, modifierZeroRegister | modifierReadonly}}
+	if !reflect.DeepEqual(edit, expected) {
+		t.Errorf("Expected edit %v, got %v.", expected, edit)
+	}
+}
@@ -0,0 +1,75 @@
+package languageserver
+
+import (
+	"strings"
+
+	lsp "go.lsp.dev/protocol"
+
+	"server/parser"
+)
+
+// SignatureHelp returns the operand signature of the instruction on the line
+// of a position, with the operand the position is in as the active parameter.
+// The active operand is the number of commas before the position.
+func SignatureHelp(doc *document, position lsp.Position) *lsp.SignatureHelp {
+	line, offset := doc.offset(position)
+	tokens := *(*doc.tokens)[line]
+	if len(tokens) == 0 || tokens[0].Type != parser.InstructionToken || offset <= tokens[0].End {
+		return nil
+	}
+	if len(tokens) > 1 && tokens[1].Type == parser.ColonToken {
+		return nil
+	}
+	if start, ok := commentStart(doc.lines[line], &tokens); ok && offset > start {
+		return nil
+	}
+
+	mnemonic := tokens[0].Value
+	info, ok := Instructions[mnemonic]
+	if !ok {
+		return nil
+	}
+	parameters := signatureParameters(info.Syntax, operandDocsFor(mnemonic))
+	if len(parameters) == 0 {
+		return nil
+	}
+
+	active := 0
+	for _, token := range tokens[1:] {
+		if token.Type == parser.CommaToken && token.End <= offset {
+			active++
+		}
+	}
+	if active >= len(parameters) {
+		active = len(parameters) - 1
+	}
+
+	return &lsp.SignatureHelp{
+		Signatures: []lsp.SignatureInformation{{
+			Label:         info.Syntax,
+			Documentation: info.Description,
+			Parameters:    parameters,
+		}},
+		ActiveParameter: uint32(active),
+	}
+}
+
+// signatureParameters splits the operands out of an instruction's syntax,
+// such as "Rt", "Rn" and "#DT_address" from "LDUR Rt, [Rn, #DT_address]",
+// and pairs each with its description.
+func signatureParameters(syntax string, docs []string) []lsp.ParameterInformation {
+	space := strings.IndexByte(syntax, ' ')
+	if space < 0 {
+		return nil
+	}
+
+	parameters := []lsp.ParameterInformation{}
+	for i, operand := range strings.Split(syntax[space+1:], ", ") {
+		parameter := lsp.ParameterInformation{Label: strings.Trim(operand, "[]")}
+		if i < len(docs) {
+			parameter.Documentation = docs[i]
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
@@ -0,0 +1,78 @@
+package languageserver
+
+import (
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestSignatureHelp(t *testing.T) {
+	tests := []struct {
+		input     string
+		character uint32
+		label     string
+		active    uint32
+		parameter string
+	}{
+		{"LDUR ", 5, "LDUR Rt, [Rn, #DT_address]", 0, "Rt"},
+		{"LDUR X1, [", 10, "LDUR Rt, [Rn, #DT_address]", 1, "Rn"},
+		{"LDUR X1, [X2, #8]", 15, "LDUR Rt, [Rn, #DT_address]", 2, "#DT_address"},
+		{"ADD X1, X2, X3", 6, "ADD Rd, Rn, Rm", 0, "Rd"},
+		{"ADD X1, X2, X3", 8, "ADD Rd, Rn, Rm", 1, "Rn"},
+		{"ADD X1, X2, X3, X4", 18, "ADD Rd, Rn, Rm", 2, "Rm"},
+		{"MOVZ X1, #5, ", 13, "MOVZ Rd, #MOV_immediate, LSL #shift", 2, "LSL #shift"},
+		{"    B.EQ ", 9, "B.EQ label", 0, "label"},
+		{"FADDS S1, ", 10, "FADDS Sd, Sn, Sm", 1, "Sn"},
+	}
+
+	for _, test := range tests {
+		doc := newDocument(uri.File("/tmp/test.legv8"), test.input)
+		help := SignatureHelp(doc, lsp.Position{Line: 0, Character: test.character})
+		if help == nil || len(help.Signatures) != 1 {
+			t.Errorf("Expected a signature, got %v. Input: %q", help, test.input)
+			continue
+		}
+		signature := help.Signatures[0]
+		if signature.Label != test.label {
+			t.Errorf("Expected signature %q, got %q. Input: %q", test.label, signature.Label, test.input)
+		}
+		if help.ActiveParameter != test.active || signature.Parameters[test.active].Label != test.parameter {
+			t.Errorf("Expected active parameter %d (%s), got %d. Input: %q", test.active, test.parameter, help.ActiveParameter, test.input)
+		}
+		if doc, ok := signature.Parameters[help.ActiveParameter].Documentation.(string); !ok || doc == "" {
+			t.Errorf("Expected documentation for %s. Input: %q", test.parameter, test.input)
+		}
+	}
+}
+
+func TestSignatureHelpNone(t *testing.T) {
+	tests := []struct {
+		input     string
+		character uint32
+	}{
+		{"", 0},
+		{"HALT ", 5},
+		{"ADD", 3},
+		{"loop: ", 6},
+		{"ADD: ", 5},
+		{"X1, X2", 3},
+		{"ADD X1, X2 // a, b", 18},
+	}
+
+	for _, test := range tests {
+		doc := newDocument(uri.File("/tmp/test.legv8"), test.input)
+		if help := SignatureHelp(doc, lsp.Position{Line: 0, Character: test.character}); help != nil {
+			t.Errorf("Expected no signature, got %v. Input: %q", help, test.input)
+		}
+	}
+}
+
+func TestOperandDocs(t *testing.T) {
+	for mnemonic, info := range Instructions {
+		parameters := signatureParameters(info.Syntax, nil)
+		if docs := operandDocsFor(mnemonic); len(docs) != len(parameters) {
+			t.Errorf("Expected %d operand descriptions for %s, got %d.", len(parameters), mnemonic, len(docs))
+		}
+	}
+}
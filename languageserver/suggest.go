@@ -0,0 +1,230 @@
+package languageserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"server/isa"
+	"server/parser"
+)
+
+// editDistance returns the Levenshtein distance between two strings: the
+// number of single-character insertions, deletions and substitutions that
+// turn a into b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxSuggestionDistance is the furthest a word can be from a name for the
+// name to be suggested in its place.
+const maxSuggestionDistance = 2
+
+// maxSuggestions is the most names a diagnostic suggests.
+const maxSuggestions = 3
+
+// mnemonicConfusions maps mnemonics students bring from other assembly
+// languages, or misremember, to the LEGv8 instructions they most likely mean.
+var mnemonicConfusions = map[string][]string{
+	// ARM and misordered LEGv8 mnemonics
+	"LDR":   {"LDUR"},
+	"LDRB":  {"LDURB"},
+	"LDRH":  {"LDURH"},
+	"LDRSW": {"LDURSW"},
+	"STR":   {"STUR"},
+	"STRB":  {"STURB"},
+	"STRH":  {"STURH"},
+	"STRW":  {"STURW"},
+	"ADDSI": {"ADDIS"},
+	"ANDSI": {"ANDIS"},
+	"SUBSI": {"SUBIS"},
+	"MOVN":  {"MOVZ"},
+
+	// MIPS
+	"LW":    {"LDUR"},
+	"LD":    {"LDUR"},
+	"SW":    {"STUR"},
+	"SD":    {"STUR"},
+	"LB":    {"LDURB"},
+	"SB":    {"STURB"},
+	"LI":    {"MOVZ"},
+	"ADDU":  {"ADD"},
+	"ADDIU": {"ADDI"},
+	"SUBU":  {"SUB"},
+	"SLL":   {"LSL"},
+	"SRL":   {"LSR"},
+	"OR":    {"ORR"},
+	"XOR":   {"EOR"},
+	"MULT":  {"MUL"},
+	"DIV":   {"SDIV"},
+	"J":     {"B"},
+	"JAL":   {"BL"},
+	"JR":    {"BR"},
+	"JALR":  {"BLR"},
+	"BEQ":   {"B.EQ"},
+	"BNE":   {"B.NE"},
+	"BEQZ":  {"CBZ"},
+	"BNEZ":  {"CBNZ"},
+
+	// x86
+	"JMP":  {"B"},
+	"CALL": {"BL"},
+	"JE":   {"B.EQ"},
+	"JNE":  {"B.NE"},
+}
+
+// immediateForms maps instructions that take only registers to the form that
+// takes an immediate in place of the last register.
+var immediateForms = map[string]string{
+	"ADD":  "ADDI",
+	"ADDS": "ADDIS",
+	"AND":  "ANDI",
+	"ANDS": "ANDIS",
+	"EOR":  "EORI",
+	"ORR":  "ORRI",
+	"SUB":  "SUBI",
+	"SUBS": "SUBIS",
+	"MOV":  "MOVZ",
+}
+
+// registerConfusions maps register names that do not exist in LEGv8 to the
+// registers they most likely mean.
+var registerConfusions = map[string][]string{
+	"X31": {"XZR"},
+	"WZR": {"XZR"},
+}
+
+// registerNames is every valid register name, in the order they are suggested.
+var registerNames []string
+
+func init() {
+	for _, prefix := range []string{"X", "S", "D"} {
+		for i := 0; i < 32; i++ {
+			if prefix == "X" && i > 30 {
+				break
+			}
+			registerNames = append(registerNames, fmt.Sprintf("%s%d", prefix, i))
+		}
+	}
+	registerNames = append(registerNames, "SP", "FP", "LR", "XZR")
+}
+
+// isRegisterName reports whether name is a register that exists.
+func isRegisterName(name string) bool {
+	if _, ok := isa.IntegerRegister(name); ok {
+		return true
+	}
+	_, ok := isa.FloatRegister(name)
+	return ok
+}
+
+// suggestMnemonics returns the mnemonics word most likely stands for: the
+// mnemonic itself in another case, a known confusion, or the nearest
+// mnemonics by edit distance.
+func suggestMnemonics(word string) []string {
+	upper := strings.ToUpper(word)
+	if _, ok := parser.KeywordInstructionTypes[upper]; ok {
+		return []string{upper}
+	}
+	if mnemonics, ok := mnemonicConfusions[upper]; ok {
+		return mnemonics
+	}
+
+	mnemonics := make([]string, 0, len(parser.KeywordInstructionTypes))
+	for mnemonic := range parser.KeywordInstructionTypes {
+		mnemonics = append(mnemonics, mnemonic)
+	}
+	sort.Strings(mnemonics)
+	return nearest(upper, mnemonics)
+}
+
+// suggestRegisters returns the registers name most likely stands for: the
+// register itself in upper case, the X register numbered as an ARM R or W
+// register, a known confusion, or the nearest registers by edit distance.
+func suggestRegisters(name string) []string {
+	upper := strings.ToUpper(name)
+	if isRegisterName(upper) {
+		return []string{upper}
+	}
+	if registers, ok := registerConfusions[upper]; ok {
+		return registers
+	}
+	if len(upper) > 1 && (upper[0] == 'R' || upper[0] == 'W') && isRegisterName("X"+upper[1:]) {
+		return []string{"X" + upper[1:]}
+	}
+	return nearest(upper, registerNames)
+}
+
+// nearest returns the candidates closest to word by edit distance. Short
+// words must be closer, since a short enough word can be rewritten into
+// anything. Among equally close candidates, those sharing a longer prefix
+// with word come first, then the candidates keep their order.
+func nearest(word string, candidates []string) []string {
+	best := (len(word) - 1) / 2
+	if best > maxSuggestionDistance {
+		best = maxSuggestionDistance
+	}
+	found := []string{}
+	for _, candidate := range candidates {
+		distance := editDistance(word, candidate)
+		if distance > best {
+			continue
+		}
+		if distance < best {
+			best, found = distance, []string{}
+		}
+		found = append(found, candidate)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return commonPrefix(word, found[i]) > commonPrefix(word, found[j])
+	})
+	if len(found) > maxSuggestions {
+		found = found[:maxSuggestions]
+	}
+	return found
+}
+
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// didYouMean phrases suggestions as a sentence to append to a message, or
+// returns an empty string when there are none.
+func didYouMean(suggestions []string) string {
+	switch len(suggestions) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf(" Did you mean %s?", suggestions[0])
+	}
+	last := len(suggestions) - 1
+	return fmt.Sprintf(" Did you mean %s or %s?", strings.Join(suggestions[:last], ", "), suggestions[last])
+}
@@ -0,0 +1,117 @@
+package languageserver
+
+import (
+	"reflect"
+	"testing"
+
+	"go.lsp.dev/uri"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"ADD", "ADD", 0},
+		{"ADDD", "ADD", 1},
+		{"ADDSI", "ADDIS", 2},
+		{"", "LDUR", 4},
+		{"CBNX", "CBNZ", 1},
+	}
+
+	for _, test := range tests {
+		if distance := editDistance(test.a, test.b); distance != test.expected {
+			t.Errorf("Expected %d, got %d. Input: %q, %q", test.expected, distance, test.a, test.b)
+		}
+	}
+}
+
+func TestSuggestMnemonics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"add", []string{"ADD"}},
+		{"ADDD", []string{"ADD", "ADDI", "ADDS"}},
+		{"ADDSI", []string{"ADDIS"}},
+		{"LDR", []string{"LDUR"}},
+		{"lw", []string{"LDUR"}},
+		{"ADDU", []string{"ADD"}},
+		{"b.eq", []string{"B.EQ"}},
+		{"LDURR", []string{"LDUR", "LDURB", "LDURD"}},
+		{"ZZZ", []string{}},
+		{"Q", []string{}},
+	}
+
+	for _, test := range tests {
+		if suggestions := suggestMnemonics(test.input); !reflect.DeepEqual(suggestions, test.expected) {
+			t.Errorf("Expected %v, got %v. Input: %q", test.expected, suggestions, test.input)
+		}
+	}
+}
+
+func TestSuggestRegisters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"x5", []string{"X5"}},
+		{"sp", []string{"SP"}},
+		{"R3", []string{"X3"}},
+		{"W30", []string{"X30"}},
+		{"X31", []string{"XZR"}},
+		{"X32", []string{"X3", "X30", "X2"}},
+		{"S32", []string{"S3", "S30", "S31"}},
+		{"loop", []string{}},
+	}
+
+	for _, test := range tests {
+		if suggestions := suggestRegisters(test.input); !reflect.DeepEqual(suggestions, test.expected) {
+			t.Errorf("Expected %v, got %v. Input: %q", test.expected, suggestions, test.input)
+		}
+	}
+}
+
+func TestDidYouMeanDiagnostics(t *testing.T) {
+	tests := []struct {
+		input   string
+		code    string
+		message string
+		start   uint32
+		end     uint32
+		fixes   []string
+	}{
+		{"  ADDD X1, X2, X3", RuleExpectedInstruction, "Expected an instruction keyword. Did you mean ADD, ADDI or ADDS?", 2, 6, []string{"ADD", "ADDI", "ADDS"}},
+		{"LW X1, X2", RuleExpectedInstruction, "Expected an instruction keyword. Did you mean LDUR?", 0, 2, []string{"LDUR"}},
+		{"ZZZ X1", RuleExpectedInstruction, "Expected an instruction keyword.", 0, 3, []string{}},
+		{"b.eq loop\nloop:", RuleExpectedInstruction, "Expected an instruction keyword. Did you mean B.EQ?", 0, 4, []string{"B.EQ"}},
+		{"ADD x5, X1, X2", RuleOperandType, "Expected a register, found a label. Did you mean X5?", 4, 6, []string{"X5"}},
+		{"ADD X1, R3, X2", RuleOperandType, "Expected a register, found a label. Did you mean X3?", 8, 10, []string{"X3"}},
+		{"MOV X1, #5", RuleOperandType, "Expected a register, found an immediate. Did you mean MOVZ?", 8, 10, []string{"MOVZ"}},
+		{"ADD X1, X32, X2", RuleUnknownRegister, "Unknown register X32. Did you mean X3, X30 or X2?", 8, 11, []string{"X3", "X30", "X2"}},
+		{"ADD X1, X2, #5", RuleOperandType, "Expected a register, found an immediate. Did you mean ADDI?", 12, 14, []string{"ADDI"}},
+		{"ADD X1, #2, X3", RuleOperandType, "Expected a register, found an immediate.", 8, 10, []string{}},
+		{"ADD X31, X1, X2", RuleUnknownRegister, "Unknown register X31. Did you mean XZR?", 4, 7, []string{"XZR"}},
+	}
+
+	for _, test := range tests {
+		doc := newDocument(uri.File("/tmp/test.legv8"), test.input)
+		diagnostics := *Analyze(doc.uri, doc.tokens, doc.Program())
+		if len(diagnostics) == 0 || diagnostics[0].Code != test.code || diagnostics[0].Message != test.message {
+			t.Errorf("Expected %s %q, got %v. Input: %q", test.code, test.message, diagnostics, test.input)
+			continue
+		}
+		if r := diagnostics[0].Range; r.Start.Character != test.start || r.End.Character != test.end {
+			t.Errorf("Expected range %d-%d, got %v. Input: %q", test.start, test.end, r, test.input)
+		}
+
+		fixes := []string{}
+		for _, action := range CodeActions(doc, diagnostics[:1]) {
+			fixes = append(fixes, action.Edit.Changes[doc.uri][0].NewText)
+		}
+		if !reflect.DeepEqual(fixes, test.fixes) {
+			t.Errorf("Expected fixes %v, got %v. Input: %q", test.fixes, fixes, test.input)
+		}
+	}
+}
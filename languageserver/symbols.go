@@ -0,0 +1,101 @@
+package languageserver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+
+	"server/ast"
+	"server/parser"
+)
+
+// DocumentSymbols returns a symbol for every label defined in a document. The
+// range of each symbol spans the label and the instructions up to the next
+// label, so that an outline reflects the structure of the program.
+func DocumentSymbols(doc *document) []lsp.DocumentSymbol {
+	symbols := []lsp.DocumentSymbol{}
+	for _, body := range labelBodies(doc.Program()) {
+		symbols = append(symbols, lsp.DocumentSymbol{
+			Name:           body.label.Name,
+			Kind:           lsp.SymbolKindFunction,
+			Range:          lspRange(body.rng),
+			SelectionRange: lspRange(body.label.Range),
+		})
+	}
+	return symbols
+}
+
+// labelBody is a label definition and the range of the instructions that
+// follow it.
+type labelBody struct {
+	label *ast.LabelDef
+	rng   ast.Range
+}
+
+func labelBodies(program *ast.Program) []labelBody {
+	bodies := []labelBody{}
+	for _, line := range program.Lines {
+		if line.Label != nil {
+			bodies = append(bodies, labelBody{line.Label, line.Label.Range})
+			continue
+		}
+		if line.Instruction != nil && len(bodies) > 0 {
+			bodies[len(bodies)-1].rng.End = line.Instruction.Range.End
+		}
+	}
+	return bodies
+}
+
+// WorkspaceSymbols returns the label definitions in every source file under
+// root whose name contains query, ignoring case. Open documents are searched
+// as they are in the editor rather than as they are saved on disk. Files are
+// searched in lexical order and hidden directories are skipped.
+func WorkspaceSymbols(root string, query string, documents *documentStore) []lsp.SymbolInformation {
+	symbols := []lsp.SymbolInformation{}
+	if root == "" {
+		return symbols
+	}
+	query = strings.ToLower(query)
+
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !parser.IsSourceFile(path) {
+			return nil
+		}
+
+		u := uri.File(path)
+		var program *ast.Program
+		if doc, ok := documents.get(u); ok {
+			program = doc.Program()
+		} else if tokens := TokenizeFile(u); tokens != nil {
+			program = parser.BuildProgram(tokens)
+		} else {
+			return nil
+		}
+
+		for _, label := range program.Labels() {
+			if !strings.Contains(strings.ToLower(label.Name), query) {
+				continue
+			}
+			symbols = append(symbols, lsp.SymbolInformation{
+				Name:          label.Name,
+				Kind:          lsp.SymbolKindFunction,
+				Location:      lsp.Location{URI: u, Range: lspRange(label.Range)},
+				ContainerName: filepath.Base(path),
+			})
+		}
+		return nil
+	})
+	return symbols
+}
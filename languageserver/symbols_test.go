@@ -0,0 +1,94 @@
+package languageserver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestDocumentSymbols(t *testing.T) {
+	doc := newDocument(uri.File("/tmp/test.legv8"), "ADD X1, X2, X3\nmain:\n    BL sum\n\n    HALT\nsum:\nend:\n    BR LR\n")
+
+	symbols := DocumentSymbols(doc)
+	expected := []struct {
+		name string
+		rng  lsp.Range
+	}{
+		{"main", lsp.Range{Start: lsp.Position{Line: 1}, End: lsp.Position{Line: 4, Character: 8}}},
+		{"sum", lsp.Range{Start: lsp.Position{Line: 5}, End: lsp.Position{Line: 5, Character: 3}}},
+		{"end", lsp.Range{Start: lsp.Position{Line: 6}, End: lsp.Position{Line: 7, Character: 9}}},
+	}
+	if len(symbols) != len(expected) {
+		t.Fatalf("Expected %d symbols, got %v.", len(expected), symbols)
+	}
+	for i, e := range expected {
+		if symbols[i].Name != e.name || symbols[i].Range != e.rng {
+			t.Errorf("Expected symbol %s spanning %v, got %s spanning %v.", e.name, e.rng, symbols[i].Name, symbols[i].Range)
+		}
+		if symbols[i].SelectionRange.Start != e.rng.Start || symbols[i].SelectionRange.End.Character != uint32(len(e.name)) {
+			t.Errorf("Incorrect selection range %v for %s.", symbols[i].SelectionRange, e.name)
+		}
+	}
+}
+
+func TestWorkspaceSymbols(t *testing.T) {
+	root, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	files := map[string]string{
+		"main.legv8":        "main:\nBL sum\nsum_loop:\nHALT",
+		"lib/sum.s":         "sum:\nBR LR",
+		"notes.txt":         "summary:",
+		".git/hooks/test.s": "sumhidden:",
+	}
+	for name, text := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	documents := newDocumentStore()
+	documents.open(uri.File(filepath.Join(root, "lib/sum.s")), "SUM:\nBR LR")
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"SUM", []string{"SUM", "sum_loop"}},
+		{"", []string{"SUM", "main", "sum_loop"}},
+		{"missing", []string{}},
+	}
+	for _, test := range tests {
+		symbols := WorkspaceSymbols(root, test.query, documents)
+		names := []string{}
+		for _, symbol := range symbols {
+			names = append(names, symbol.Name)
+		}
+		if len(names) != len(test.expected) {
+			t.Errorf("Expected symbols %v, got %v. Input: %q", test.expected, names, test.query)
+			continue
+		}
+		for i := range names {
+			if names[i] != test.expected[i] {
+				t.Errorf("Expected symbols %v, got %v. Input: %q", test.expected, names, test.query)
+				break
+			}
+		}
+	}
+
+	symbols := WorkspaceSymbols(root, "main", documents)
+	if len(symbols) != 1 || symbols[0].Location.URI != uri.File(filepath.Join(root, "main.legv8")) || symbols[0].ContainerName != "main.legv8" {
+		t.Errorf("Incorrect location for main: %v.", symbols)
+	}
+}
@@ -0,0 +1,109 @@
+package lint
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"server/languageserver"
+)
+
+// Exit codes of the lint command.
+const (
+	ExitClean  = 0
+	ExitErrors = 1
+	ExitUsage  = 2
+)
+
+// ruleFlag collects -rule code=severity flags.
+type ruleFlag map[string]string
+
+func (f ruleFlag) String() string {
+	return ""
+}
+
+func (f ruleFlag) Set(value string) error {
+	equals := strings.IndexByte(value, '=')
+	if equals < 0 {
+		return fmt.Errorf("expected code=severity, got %s", value)
+	}
+	f[value[:equals]] = value[equals+1:]
+	return nil
+}
+
+// WriteRules lists the code, default severity, name and description of
+// every rule.
+func WriteRules(w io.Writer) {
+	for _, rule := range languageserver.Rules {
+		fmt.Fprintf(w, "%s  %-7s  %-20s  %s\n", rule.Code, severityName(rule.Severity), rule.Name, rule.Description)
+	}
+}
+
+// writers are the output formats, by name.
+var writers = map[string]func(io.Writer, []*Result) error{
+	"text":  WriteText,
+	"json":  WriteJSON,
+	"sarif": WriteSARIF,
+}
+
+// Main runs the lint command with the given arguments, returning its exit
+// code. Files with errors give ExitErrors; warnings alone do not.
+func Main(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: server lint [-format text|json|sarif] [-rule code=severity]... [file | directory | glob]...")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "text", "output `format`: text, json or sarif")
+	listRules := flags.Bool("list-rules", false, "list every rule and exit")
+	settings := languageserver.RuleSettings{Rules: map[string]string{}}
+	flags.Var(ruleFlag(settings.Rules), "rule", "set a rule to off, error, warning, information or hint, as `code=severity` (repeatable)")
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *listRules {
+		WriteRules(stdout)
+		return ExitClean
+	}
+	if err := settings.Validate(); err != nil {
+		fmt.Fprintf(stderr, "lint: %v\n", err)
+		return ExitUsage
+	}
+	write, ok := writers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "lint: unknown format %s\n", *format)
+		return ExitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	paths, err := Expand(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "lint: %v\n", err)
+		return ExitUsage
+	}
+
+	results := []*Result{}
+	for _, path := range paths {
+		result, err := File(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "lint: %v\n", err)
+			return ExitUsage
+		}
+		result.Diagnostics = settings.Apply(result.Diagnostics)
+		results = append(results, result)
+	}
+
+	if err := write(stdout, results); err != nil {
+		fmt.Fprintf(stderr, "lint: %v\n", err)
+		return ExitUsage
+	}
+	if HasErrors(results) {
+		return ExitErrors
+	}
+	return ExitClean
+}
@@ -0,0 +1,171 @@
+package lint
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+
+	"server/languageserver"
+)
+
+// file is the JSON form of a Result.
+type file struct {
+	Path        string           `json:"path"`
+	Diagnostics []lsp.Diagnostic `json:"diagnostics"`
+}
+
+// WriteJSON writes the results as an array of files, each with the
+// diagnostics reported for it in the form the language server sends them.
+func WriteJSON(w io.Writer, results []*Result) error {
+	files := make([]file, len(results))
+	for i, result := range results {
+		files[i] = file{result.Path, result.Diagnostics}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(files)
+}
+
+// The subset of SARIF 2.1.0 needed to report diagnostics.
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string             `json:"id"`
+		Name                 string             `json:"name,omitempty"`
+		ShortDescription     *sarifMessage      `json:"shortDescription,omitempty"`
+		DefaultConfiguration *sarifRuleDefaults `json:"defaultConfiguration,omitempty"`
+	}
+	sarifRuleDefaults struct {
+		Level string `json:"level"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           sarifRegion           `json:"region"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+		EndLine     int `json:"endLine"`
+		// EndColumn is omitted for diagnostics that run to the end of the line.
+		EndColumn int `json:"endColumn,omitempty"`
+	}
+)
+
+// artifactURI is the SARIF location of a file. Relative paths are kept
+// relative so viewers can resolve them against the repository root.
+func artifactURI(path string) string {
+	if filepath.IsAbs(path) {
+		return string(uri.File(path))
+	}
+	return filepath.ToSlash(path)
+}
+
+// sarifLevel maps a severity to a SARIF result level.
+func sarifLevel(severity lsp.DiagnosticSeverity) string {
+	switch severity {
+	case lsp.DiagnosticSeverityWarning:
+		return "warning"
+	case lsp.DiagnosticSeverityInformation, lsp.DiagnosticSeverityHint:
+		return "note"
+	}
+	return "error"
+}
+
+// WriteSARIF writes the results as a SARIF 2.1.0 log with a single run.
+// Each diagnostic's code is used as its rule ID, and the rules used are
+// described from the rule catalogue.
+func WriteSARIF(w io.Writer, results []*Result) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:  "legv8-language-server",
+			Rules: []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := map[string]bool{}
+	for _, result := range results {
+		for _, d := range result.Diagnostics {
+			ruleID, _ := d.Code.(string)
+			rules[ruleID] = true
+
+			region := sarifRegion{
+				StartLine:   int(d.Range.Start.Line) + 1,
+				StartColumn: int(d.Range.Start.Character) + 1,
+				EndLine:     int(d.Range.End.Line) + 1,
+			}
+			if d.Range.End.Character != math.MaxUint32 {
+				region.EndColumn = int(d.Range.End.Character) + 1
+			}
+
+			run.Results = append(run.Results, sarifResult{
+				RuleID:  ruleID,
+				Level:   sarifLevel(d.Severity),
+				Message: sarifMessage{d.Message},
+				Locations: []sarifLocation{{sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{artifactURI(result.Path)},
+					Region:           region,
+				}}},
+			})
+		}
+	}
+
+	ids := make([]string, 0, len(rules))
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		rule := sarifRule{ID: id}
+		if r, ok := languageserver.LookupRule(id); ok {
+			rule.Name = r.Name
+			rule.ShortDescription = &sarifMessage{r.Description}
+			rule.DefaultConfiguration = &sarifRuleDefaults{sarifLevel(r.Severity)}
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,97 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+
+	lsp "go.lsp.dev/protocol"
+
+	"server/languageserver"
+)
+
+var exportResults = []*Result{
+	{
+		Path: "labs/lab1.legv8",
+		Diagnostics: []lsp.Diagnostic{
+			{
+				Range:    lsp.Range{Start: lsp.Position{Line: 0, Character: 7}, End: lsp.Position{Line: 0, Character: 9}},
+				Severity: lsp.DiagnosticSeverityError,
+				Code:     languageserver.RuleExpectedComma,
+				Message:  "Expected a comma.",
+				Source:   "compiler",
+			},
+			{
+				Range:    lsp.Range{Start: lsp.Position{Line: 2, Character: 17}, End: lsp.Position{Line: 2, Character: math.MaxUint32}},
+				Severity: lsp.DiagnosticSeverityError,
+				Code:     languageserver.RuleTrailingTokens,
+				Message:  "Expected end of line.",
+				Source:   "compiler",
+			},
+		},
+	},
+	{Path: "labs/lab2.legv8", Diagnostics: []lsp.Diagnostic{}},
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteJSON(&out, exportResults); err != nil {
+		t.Fatalf("Unexpected error %v.", err)
+	}
+
+	var files []file
+	if err := json.Unmarshal(out.Bytes(), &files); err != nil {
+		t.Fatalf("Invalid JSON %v: %s", err, out.String())
+	}
+	if len(files) != 2 || files[0].Path != "labs/lab1.legv8" || len(files[0].Diagnostics) != 2 || len(files[1].Diagnostics) != 0 {
+		t.Fatalf("Incorrect files %+v.", files)
+	}
+	if files[0].Diagnostics[0].Code != languageserver.RuleExpectedComma {
+		t.Errorf("Expected code %s, got %v.", languageserver.RuleExpectedComma, files[0].Diagnostics[0].Code)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteSARIF(&out, exportResults); err != nil {
+		t.Fatalf("Unexpected error %v.", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("Invalid JSON %v: %s", err, out.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Incorrect log %+v.", log)
+	}
+	run := log.Runs[0]
+
+	rules := run.Tool.Driver.Rules
+	if len(rules) != 2 || rules[0].ID != languageserver.RuleExpectedComma || rules[1].ID != languageserver.RuleTrailingTokens {
+		t.Errorf("Incorrect rules %+v.", rules)
+	}
+
+	expected := []struct {
+		ruleID string
+		region sarifRegion
+	}{
+		{languageserver.RuleExpectedComma, sarifRegion{StartLine: 1, StartColumn: 8, EndLine: 1, EndColumn: 10}},
+		{languageserver.RuleTrailingTokens, sarifRegion{StartLine: 3, StartColumn: 18, EndLine: 3}},
+	}
+	if len(run.Results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d.", len(expected), len(run.Results))
+	}
+	for i, result := range run.Results {
+		if result.RuleID != expected[i].ruleID || result.Level != "error" {
+			t.Errorf("Expected rule %s, got %s at level %s.", expected[i].ruleID, result.RuleID, result.Level)
+		}
+		location := result.Locations[0].PhysicalLocation
+		if location.ArtifactLocation.URI != "labs/lab1.legv8" {
+			t.Errorf("Incorrect artifact location %s.", location.ArtifactLocation.URI)
+		}
+		if location.Region != expected[i].region {
+			t.Errorf("Expected region %+v, got %+v.", expected[i].region, location.Region)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package lint runs the language server's diagnostics over files on disk,
+// for use from the command line.
+package lint
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	lsp "go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+
+	"server/languageserver"
+	"server/parser"
+)
+
+// Result is the diagnostics reported for a single file, ordered by position.
+type Result struct {
+	Path        string
+	Diagnostics []lsp.Diagnostic
+}
+
+// Expand resolves each argument, which may be a file, a directory or a glob,
+// to the files it names. Directories are searched recursively for files with
+// one of the parser.SourceExtensions.
+func Expand(args []string) ([]string, error) {
+	paths := []string{}
+	seen := map[string]bool{}
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %v", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %s", arg)
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(match)
+				continue
+			}
+			err = filepath.Walk(match, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !info.IsDir() && parser.IsSourceFile(path) {
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return paths, nil
+}
+
+// File runs the same checks as the language server on a file.
+func File(path string) (*Result, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(absolute); err != nil {
+		return nil, err
+	}
+
+	u := uri.File(absolute)
+	tokens := languageserver.TokenizeFile(u)
+	if tokens == nil {
+		return nil, fmt.Errorf("cannot read %s", path)
+	}
+
+	diagnostics := *languageserver.Analyze(u, tokens, parser.BuildProgram(tokens))
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Range.Start, diagnostics[j].Range.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Character < b.Character
+	})
+	return &Result{Path: path, Diagnostics: diagnostics}, nil
+}
+
+// HasErrors reports whether any result has a diagnostic of error severity.
+func HasErrors(results []*Result) bool {
+	for _, result := range results {
+		for _, diagnostic := range result.Diagnostics {
+			if diagnostic.Severity == lsp.DiagnosticSeverityError {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// severityName is the name of a severity in compiler-style output.
+func severityName(severity lsp.DiagnosticSeverity) string {
+	switch severity {
+	case lsp.DiagnosticSeverityWarning:
+		return "warning"
+	case lsp.DiagnosticSeverityInformation:
+		return "info"
+	case lsp.DiagnosticSeverityHint:
+		return "hint"
+	}
+	return "error"
+}
+
+// WriteText writes each diagnostic as file:line:col: severity: message, with
+// one-based lines and columns.
+func WriteText(w io.Writer, results []*Result) error {
+	for _, result := range results {
+		for _, d := range result.Diagnostics {
+			_, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s\n", result.Path, d.Range.Start.Line+1, d.Range.Start.Character+1, severityName(d.Severity), d.Message)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package lint
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"server/languageserver"
+)
+
+// writeFiles creates files in a temporary directory and returns its path.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExpand(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.legv8":       "",
+		"b.s":           "",
+		"notes.txt":     "",
+		"sub/c.LEGV8":   "",
+		"sub/deep/d.s":  "",
+		"other/e.legv8": "",
+	})
+
+	inputs := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"a.legv8"}, []string{"a.legv8"}},
+		{[]string{"*.legv8", "a.legv8"}, []string{"a.legv8"}},
+		{[]string{"notes.txt"}, []string{"notes.txt"}},
+		{[]string{"sub"}, []string{"sub/c.LEGV8", "sub/deep/d.s"}},
+		{[]string{"*/e.legv8", "b.s"}, []string{"other/e.legv8", "b.s"}},
+	}
+
+	for _, in := range inputs {
+		args := []string{}
+		for _, arg := range in.args {
+			args = append(args, filepath.Join(dir, arg))
+		}
+		paths, err := Expand(args)
+		if err != nil {
+			t.Errorf("Unexpected error %v. Input: %v", err, in.args)
+			continue
+		}
+		if len(paths) != len(in.expected) {
+			t.Errorf("Expected %v, got %v. Input: %v", in.expected, paths, in.args)
+			continue
+		}
+		for i := range paths {
+			if paths[i] != filepath.Join(dir, in.expected[i]) {
+				t.Errorf("Expected %v, got %v. Input: %v", in.expected, paths, in.args)
+				break
+			}
+		}
+	}
+
+	if _, err := Expand([]string{filepath.Join(dir, "*.missing")}); err == nil {
+		t.Errorf("Expected an error for a pattern matching no files.")
+	}
+}
+
+func TestCommand(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"clean.legv8":  "loop:\nADDI X0, X0, #1\nB loop",
+		"broken.legv8": "B nowhere\nADD X1 X2, X3",
+	})
+	clean, broken := filepath.Join(dir, "clean.legv8"), filepath.Join(dir, "broken.legv8")
+
+	inputs := []struct {
+		args     []string
+		code     int
+		expected string
+	}{
+		{[]string{clean}, ExitClean, ""},
+		{[]string{broken, clean}, ExitErrors, broken + ":1:3: error: Label 'nowhere' is not defined.\n" + broken + ":2:8: error: Expected a comma.\n"},
+		{[]string{"-rule", "undefined-label=warning", "-rule", "LEGV8-E001=off", broken}, ExitClean, broken + ":1:3: warning: Label 'nowhere' is not defined.\n"},
+		{[]string{"-rule", "no-such-rule=off", broken}, ExitUsage, ""},
+		{[]string{}, ExitUsage, ""},
+		{[]string{filepath.Join(dir, "missing.legv8")}, ExitUsage, ""},
+	}
+
+	for _, in := range inputs {
+		var stdout, stderr bytes.Buffer
+		if code := Main(in.args, &stdout, &stderr); code != in.code {
+			t.Errorf("Expected exit code %d, got %d. Input: %v", in.code, code, in.args)
+		}
+		if stdout.String() != in.expected {
+			t.Errorf("Expected output %q, got %q. Input: %v", in.expected, stdout.String(), in.args)
+		}
+	}
+}
+
+func TestListRules(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := Main([]string{"-list-rules"}, &stdout, &stderr); code != ExitClean {
+		t.Errorf("Expected exit code %d, got %d.", ExitClean, code)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != len(languageserver.Rules) || !strings.HasPrefix(lines[0], "LEGV8-E001  error    expected-comma") {
+		t.Errorf("Incorrect rule list %q.", stdout.String())
+	}
+}
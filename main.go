@@ -2,15 +2,33 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"go.lsp.dev/jsonrpc2"
 	"io"
 	"os"
 	"os/signal"
+	"server/dap"
+	"server/format"
 	"server/languageserver"
+	"server/lint"
 	"syscall"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "dap":
+			if err := dap.Serve(os.Stdin, os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		case "lint":
+			os.Exit(lint.Main(os.Args[2:], os.Stdout, os.Stderr))
+		case "fmt":
+			os.Exit(format.Main(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill, syscall.SIGTERM)
 	defer stop()
@@ -0,0 +1,15 @@
+package parser
+
+// isLabelDefinition reports whether a tokenized line defines a label.
+func isLabelDefinition(tokens *[]*Token) bool {
+	return len(*tokens) >= 2 && (*tokens)[0].Type == LabelToken && (*tokens)[1].Type == ColonToken
+}
+
+// isInstructionLine reports whether a tokenized line holds an instruction that
+// occupies an address in the assembled program.
+func isInstructionLine(tokens *[]*Token) bool {
+	if len(*tokens) == 0 || (*tokens)[0].Type != InstructionToken {
+		return false
+	}
+	return len(*tokens) < 2 || (*tokens)[1].Type != ColonToken
+}
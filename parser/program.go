@@ -0,0 +1,168 @@
+// Package parser tokenizes LEGv8 source and builds its syntax tree. Like ast,
+// it has no dependency on the language server protocol.
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"server/ast"
+	"server/isa"
+)
+
+// ParseProgram parses LEGv8 source text into a syntax tree.
+func ParseProgram(text string) *ast.Program {
+	lines := SplitLines(text)
+	tokens := make([]*[]*Token, len(lines))
+	for i, line := range lines {
+		tokens[i] = TokenizeLine(line)
+	}
+	return BuildProgram(&tokens)
+}
+
+// BuildProgram builds a syntax tree from tokenized lines.
+func BuildProgram(lines *[]*[]*Token) *ast.Program {
+	program := &ast.Program{Lines: make([]*ast.Line, 0, len(*lines))}
+
+	index := 0
+	for i, tokens := range *lines {
+		line := &ast.Line{Number: i}
+		program.Lines = append(program.Lines, line)
+
+		switch {
+		case isLabelDefinition(tokens):
+			label := (*tokens)[0]
+			line.Label = &ast.LabelDef{
+				Name:  label.Value,
+				Range: astRange(i, label.Start, label.End),
+				Index: index,
+			}
+		case isInstructionLine(tokens):
+			line.Instruction = buildInstruction(i, tokens, index)
+			index += size(line.Instruction.Mnemonic)
+		}
+	}
+
+	return program
+}
+
+// buildInstruction builds the syntax tree of an instruction line. Operands are
+// collected from the tokens present even when the line is malformed.
+func buildInstruction(lineNumber int, tokens *[]*Token, index int) *ast.Instruction {
+	first := (*tokens)[0]
+	last := (*tokens)[len(*tokens)-1]
+
+	instruction := &ast.Instruction{
+		Mnemonic:      first.Value,
+		MnemonicRange: astRange(lineNumber, first.Start, first.End),
+		Format:        format(first.Value),
+		Operands:      []ast.Operand{},
+		Range:         astRange(lineNumber, first.Start, last.End),
+		Index:         index,
+		Valid:         len(Check(tokens, Expected(first))) == 0,
+	}
+
+	var memory *ast.MemoryRef
+	var shift *ast.Shift
+	for _, token := range (*tokens)[1:] {
+		var operand ast.Operand
+		switch token.Type {
+		case RegisterToken:
+			register := &ast.Register{
+				Name:  token.Value,
+				Range: astRange(lineNumber, token.Start, token.End),
+			}
+			if memory != nil {
+				if memory.Base == nil {
+					memory.Base = register
+				}
+				continue
+			}
+			operand = register
+		case NumberToken:
+			// out of range values are clamped, which keeps them out of range
+			value, _ := strconv.ParseInt(token.Value[1:], 10, 64)
+			immediate := &ast.Immediate{
+				Value: value,
+				Text:  token.Value,
+				Range: astRange(lineNumber, token.Start, token.End),
+			}
+			if memory != nil {
+				if memory.Offset == nil {
+					memory.Offset = immediate
+				}
+				continue
+			}
+			if shift != nil && shift.Amount == nil {
+				shift.Amount = immediate
+				shift.Range.End = immediate.Range.End
+				continue
+			}
+			operand = immediate
+		case LabelToken:
+			operand = &ast.LabelRef{
+				Name:  token.Value,
+				Range: astRange(lineNumber, token.Start, token.End),
+			}
+		case ShiftToken:
+			shift = &ast.Shift{Range: astRange(lineNumber, token.Start, token.End)}
+			operand = shift
+		case LeftBracketToken:
+			memory = &ast.MemoryRef{Range: astRange(lineNumber, token.Start, token.End)}
+			operand = memory
+		case RightBracketToken:
+			if memory != nil {
+				memory.Range.End.Column = token.End
+				memory = nil
+			}
+			continue
+		default:
+			continue
+		}
+		instruction.Operands = append(instruction.Operands, operand)
+	}
+
+	// an unterminated memory reference extends to the end of the line
+	if memory != nil {
+		memory.Range.End.Column = last.End
+	}
+
+	return instruction
+}
+
+func astRange(line int, start int, end int) ast.Range {
+	return ast.Range{
+		Start: ast.Position{Line: line, Column: start},
+		End:   ast.Position{Line: line, Column: end},
+	}
+}
+
+// format returns the encoding format of an instruction. Pseudo-instructions
+// have the format of the instruction they are assembled as.
+func format(mnemonic string) isa.Format {
+	if pseudo, ok := isa.PseudoInstructions[mnemonic]; ok {
+		mnemonic = strings.Fields(pseudo.Expansion[0])[0]
+	}
+	if info, ok := isa.Instructions[mnemonic]; ok {
+		return info.Format
+	}
+	return 0
+}
+
+// size returns the number of instructions an instruction is assembled as,
+// which is more than one for some pseudo-instructions.
+func size(mnemonic string) int {
+	if pseudo, ok := isa.PseudoInstructions[mnemonic]; ok {
+		return len(pseudo.Expansion)
+	}
+	return 1
+}
+
+// SplitLines splits text into lines, accepting both \n and \r\n line endings.
+func SplitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"testing"
+
+	"server/ast"
+	"server/isa"
+)
+
+func TestParseProgram(t *testing.T) {
+	program := ParseProgram("main:\nLDUR X1, [SP, #-8]\n\nMOVZ X2, #255, LSL #16\nloop:\nCBZ X1, loop\nADDI X0 X1")
+
+	if len(program.Lines) != 7 {
+		t.Fatalf("Expected 7 lines, got %d.", len(program.Lines))
+	}
+
+	labels := program.Labels()
+	if len(labels) != 2 || labels[0].Name != "main" || labels[0].Index != 0 || labels[1].Name != "loop" || labels[1].Index != 2 {
+		t.Errorf("Incorrect labels %v.", labels)
+	}
+
+	instructions := program.Instructions()
+	if len(instructions) != 4 {
+		t.Fatalf("Expected 4 instructions, got %d.", len(instructions))
+	}
+
+	ldur := instructions[0]
+	if ldur.Mnemonic != "LDUR" || ldur.Format != isa.D || !ldur.Valid || len(ldur.Operands) != 2 {
+		t.Fatalf("Incorrect LDUR instruction %+v.", ldur)
+	}
+	if r, ok := ldur.Operands[0].(*ast.Register); !ok || r.Name != "X1" {
+		t.Errorf("Expected register X1, got %+v.", ldur.Operands[0])
+	}
+	memory, ok := ldur.Operands[1].(*ast.MemoryRef)
+	if !ok || memory.Base.Name != "SP" || memory.Offset.Value != -8 {
+		t.Errorf("Expected memory reference [SP, #-8], got %+v.", ldur.Operands[1])
+	} else if memory.Range.Start.Column != 9 || memory.Range.End.Column != 18 {
+		t.Errorf("Incorrect memory reference range %+v.", memory.Range)
+	}
+
+	movz := instructions[1]
+	if shift, ok := movz.Operands[2].(*ast.Shift); !ok || shift.Amount.Value != 16 {
+		t.Errorf("Expected shift LSL #16, got %+v.", movz.Operands[2])
+	}
+	if immediates := movz.Immediates(); len(immediates) != 2 || immediates[0].Value != 255 {
+		t.Errorf("Incorrect immediates %v.", immediates)
+	}
+
+	cbz := instructions[2]
+	if cbz.Index != 2 || cbz.Range.Start.Line != 5 {
+		t.Errorf("Incorrect CBZ position %+v.", cbz)
+	}
+	if refs := program.LabelRefs("loop"); len(refs) != 1 || refs[0].Range.Start.Column != 8 {
+		t.Errorf("Incorrect references to loop %v.", refs)
+	}
+
+	if instructions[3].Valid {
+		t.Errorf("Expected malformed ADDI to be invalid.")
+	}
+}
+
+func TestPseudoInstructions(t *testing.T) {
+	program := ParseProgram("MNEG X1, X2, X3\nafter:\nADR X4, after")
+
+	// MNEG is assembled as two instructions, so it takes two indices
+	if labels := program.Labels(); len(labels) != 1 || labels[0].Index != 2 {
+		t.Errorf("Incorrect labels %v.", labels)
+	}
+
+	instructions := program.Instructions()
+	if len(instructions) != 2 {
+		t.Fatalf("Expected 2 instructions, got %d.", len(instructions))
+	}
+	if mneg := instructions[0]; mneg.Format != isa.R || !mneg.Valid {
+		t.Errorf("Incorrect MNEG instruction %+v.", mneg)
+	}
+	adr := instructions[1]
+	if adr.Format != isa.I || !adr.Valid || adr.Index != 2 {
+		t.Errorf("Incorrect ADR instruction %+v.", adr)
+	}
+	if ref, ok := adr.Operands[1].(*ast.LabelRef); !ok || ref.Name != "after" {
+		t.Errorf("Expected label reference after, got %+v.", adr.Operands[1])
+	}
+}
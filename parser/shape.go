@@ -0,0 +1,164 @@
+package parser
+
+// MismatchKind classifies the ways a line can differ from the tokens its
+// instruction expects.
+type MismatchKind int8
+
+const (
+	// Missing is an expected token that is not present.
+	Missing MismatchKind = iota
+	// WrongOperand is an operand of the wrong kind in an operand's place.
+	WrongOperand
+	// UnexpectedBracket is a bracket where none belongs.
+	UnexpectedBracket
+	// Trailing is a token after the last expected token.
+	Trailing
+)
+
+// Mismatch is a difference between a line and the tokens expected on it.
+type Mismatch struct {
+	Kind     MismatchKind
+	Expected TokenType
+
+	// Token is the token found in place of Expected. It is nil when the line
+	// ends before Expected.
+	Token *Token
+
+	// Last is set when Expected is the last token of the line.
+	Last bool
+}
+
+// Check compares a line against the tokens expected for its instruction,
+// returning every mismatch. After a mismatch the line is resynchronized on
+// the next comma or bracket so later operands are still checked.
+func Check(tokens *[]*Token, expected *[]TokenType) []Mismatch {
+	mismatches := []Mismatch{}
+
+	// the first token is the instruction, which selected the expected tokens
+	i, j := 1, 1
+	for i < len(*tokens) && j < len(*expected) {
+		token := (*tokens)[i]
+		want := (*expected)[j]
+		if token.Type == want {
+			i++
+			j++
+			continue
+		}
+
+		mismatch := Mismatch{Kind: Missing, Expected: want, Token: token, Last: j == len(*expected)-1}
+		switch {
+		case IsPunctuation(want) && !IsPunctuation(token.Type):
+			// a separator is missing. continue as if it were present.
+			j++
+		case !IsPunctuation(want) && IsPunctuation(token.Type):
+			// an operand is missing before this separator
+			j++
+		case !IsPunctuation(want):
+			// an operand of the wrong kind is in this operand's place
+			mismatch.Kind = WrongOperand
+			i++
+			j++
+		case token.Type == RightBracketToken && closes(*expected, j) > j:
+			// the memory operand closes early. whatever it still expected is
+			// missing, and the bracket is its close.
+			k := closes(*expected, j)
+			mismatch.Expected = firstOperand((*expected)[j:k])
+			j = k
+		case IsBracket(token.Type) && !IsBracket(want):
+			// a bracket where none belongs. skip over it.
+			mismatch.Kind = UnexpectedBracket
+			i++
+		default:
+			// a different separator than expected is present
+			j++
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+
+	if i < len(*tokens) {
+		mismatches = append(mismatches, Mismatch{Kind: Trailing, Expected: EOLToken, Token: (*tokens)[i]})
+	}
+
+	if j < len(*expected) && optionalFrom[(*tokens)[0].Value] != j {
+		mismatches = append(mismatches, Mismatch{Kind: Missing, Expected: (*expected)[j], Last: j == len(*expected)-1})
+	}
+
+	return mismatches
+}
+
+// closes returns the index of the right bracket expected at or after j, or
+// -1 if there is none.
+func closes(expected []TokenType, j int) int {
+	for k := j; k < len(expected); k++ {
+		if expected[k] == RightBracketToken {
+			return k
+		}
+	}
+	return -1
+}
+
+// firstOperand returns the first operand among expected tokens.
+func firstOperand(expected []TokenType) TokenType {
+	for _, t := range expected {
+		if !IsPunctuation(t) {
+			return t
+		}
+	}
+	return expected[0]
+}
+
+// IsPunctuation reports whether a token type separates operands.
+func IsPunctuation(t TokenType) bool {
+	return t == CommaToken || t == LeftBracketToken || t == RightBracketToken || t == ColonToken
+}
+
+// IsBracket reports whether a token type is a bracket of a memory operand.
+func IsBracket(t TokenType) bool {
+	return t == LeftBracketToken || t == RightBracketToken
+}
+
+// Expected returns the tokens expected on a line starting with the instruction token.
+func Expected(instruction *Token) *[]TokenType {
+	if exp, ok := mnemonicExpected[instruction.Value]; ok {
+		return exp
+	}
+	return expected[instruction.InstructionType]
+}
+
+var expected map[InstructionType]*[]TokenType
+
+// mnemonicExpected overrides expected for instructions whose operands differ
+// from the rest of their format.
+var mnemonicExpected map[string]*[]TokenType
+
+// optionalFrom is the index in expected from which the trailing operands of
+// an instruction may be left out, such as the register of RET.
+var optionalFrom = map[string]int{"RET": 1}
+
+func init() {
+	expected = map[InstructionType](*[]TokenType){
+		R:      &[]TokenType{InstructionToken, RegisterToken, CommaToken, RegisterToken, CommaToken, RegisterToken},
+		I:      &[]TokenType{InstructionToken, RegisterToken, CommaToken, RegisterToken, CommaToken, NumberToken},
+		IM:     &[]TokenType{InstructionToken, RegisterToken},
+		D:      &[]TokenType{InstructionToken, RegisterToken, CommaToken, LeftBracketToken, RegisterToken, CommaToken, NumberToken, RightBracketToken},
+		B:      &[]TokenType{InstructionToken, LabelToken},
+		BR:     &[]TokenType{InstructionToken, RegisterToken},
+		CB:     &[]TokenType{InstructionToken, RegisterToken, CommaToken, LabelToken},
+		IGNORE: &[]TokenType{InstructionToken},
+		IW:     &[]TokenType{InstructionToken, RegisterToken, CommaToken, NumberToken, CommaToken, ShiftToken, NumberToken},
+	}
+
+	twoRegisters := &[]TokenType{InstructionToken, RegisterToken, CommaToken, RegisterToken}
+	mnemonicExpected = map[string]*[]TokenType{
+		"FCMPS": twoRegisters,
+		"FCMPD": twoRegisters,
+		"MOV":   twoRegisters,
+		"CMP":   twoRegisters,
+		"CMPI":  {InstructionToken, RegisterToken, CommaToken, NumberToken},
+		"LDA":   expected[D],
+		"ADR":   {InstructionToken, RegisterToken, CommaToken, LabelToken},
+		"MNEG":  expected[R],
+		"SMULL": expected[R],
+		"UMULL": expected[R],
+	}
+}
@@ -0,0 +1,19 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// SourceExtensions are the file extensions of LEGv8 source files.
+var SourceExtensions = []string{".legv8", ".s"}
+
+// IsSourceFile reports whether path has one of the SourceExtensions.
+func IsSourceFile(path string) bool {
+	for _, extension := range SourceExtensions {
+		if strings.EqualFold(filepath.Ext(path), extension) {
+			return true
+		}
+	}
+	return false
+}
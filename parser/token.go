@@ -1,4 +1,4 @@
-package languageserver
+package parser
 
 var KeywordInstructionTypes map[string]InstructionType
 
@@ -16,6 +16,8 @@ const (
 	CB
 	IM
 	IGNORE
+	IW
+	PSEUDO
 	UNKNOWN
 )
 
@@ -45,6 +47,7 @@ const (
 	EOLToken
 	NumberToken
 	ColonToken
+	ShiftToken
 )
 
 func (inst InstructionType) String() string {
@@ -65,6 +68,10 @@ func (inst InstructionType) String() string {
 		return "IM"
 	case IGNORE:
 		return "IGNORE"
+	case IW:
+		return "IW"
+	case PSEUDO:
+		return "PSEUDO"
 	}
 	return "UNKNOWN"
 }
@@ -89,6 +96,8 @@ func (t TokenType) String() string {
 		return "Immediate"
 	case ColonToken:
 		return "Colon"
+	case ShiftToken:
+		return "Shift"
 	}
 	return "Unknown"
 }
@@ -106,12 +115,14 @@ func init() {
 
 	iTypeInstructions := []string{"ADDI", "SUBI", "ANDI", "ADDIS", "ORRI", "EORI", "SUBIS", "ANDIS", "LSL", "LSR"}
 	imTypeInstructions := []string{"PRNT"}
-	dTypeInstructions := []string{"STURB", "LDURB", "STURH", "LDURH", "STURW", "LDURSW", "STXR", "LDXR", "STUR", "LDUR"}
-	rTypeInstructions := []string{"FDIVS", "FMULS", "FCMPS", "FADDS", "FSUBS", "FMULD", "FDIVD", "FCMPD", "FADDD", "FSUBD", "AND", "ADD", "SDIV", "UDIV", "MUL", "SMULH", "UMULH", "ORR", "ADDS", "STURS", "LDURS", "EOR", "SUB", "ANDS", "SUBS", "STURD", "LDURD"}
+	dTypeInstructions := []string{"STURB", "LDURB", "STURH", "LDURH", "STURW", "LDURSW", "STXR", "LDXR", "STUR", "LDUR", "STURS", "LDURS", "STURD", "LDURD"}
+	rTypeInstructions := []string{"FDIVS", "FMULS", "FCMPS", "FADDS", "FSUBS", "FMULD", "FDIVD", "FCMPD", "FADDD", "FSUBD", "AND", "ADD", "SDIV", "UDIV", "MUL", "SMULH", "UMULH", "ORR", "ADDS", "EOR", "SUB", "ANDS", "SUBS"}
 	bTypeInstructions := []string{"B.EQ", "B.GT", "B.NE", "B.HS", "B.LO", "B.MI", "B.PL", "B.VS", "B.VC", "B.HI", "B.LS", "B.GE", "B.LT", "B.LE", "B", "BL"}
 	cbTypeInstructions := []string{"CBZ", "CBNZ"}
-	brTypeInstructions := []string{"BR"}
+	brTypeInstructions := []string{"BR", "BLR", "RET"}
 	ignoreTypeInstructions := []string{"PRNL", "DUMP", "HALT"}
+	iwTypeInstructions := []string{"MOVZ", "MOVK"}
+	pseudoTypeInstructions := []string{"MOV", "CMP", "CMPI", "LDA", "ADR", "MNEG", "SMULL", "UMULL"}
 
 	for _, v := range iTypeInstructions {
 		KeywordInstructionTypes[v] = I
@@ -137,4 +148,10 @@ func init() {
 	for _, v := range ignoreTypeInstructions {
 		KeywordInstructionTypes[v] = IGNORE
 	}
+	for _, v := range iwTypeInstructions {
+		KeywordInstructionTypes[v] = IW
+	}
+	for _, v := range pseudoTypeInstructions {
+		KeywordInstructionTypes[v] = PSEUDO
+	}
 }
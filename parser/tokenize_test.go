@@ -1,4 +1,4 @@
-package languageserver
+package parser
 
 import (
 	"testing"
@@ -17,6 +17,13 @@ func TestTokenize(t *testing.T) {
 		"AND X12, X10, XZR",
 		"AND X12, X10, SP",
 		"ZZZ",
+		"LDUR X1, [SP, #-8]",
+		"FADDS S0, S1, S31",
+		"LDURD D2, [X1, #8]",
+		"MOVZ X1, #255, LSL #16",
+		"\tCBZ\tX1, top",
+		"S1: B S1",
+		"CBZ X1, D2",
 	}
 
 	expected_outs := []*[]Token{
@@ -91,6 +98,61 @@ func TestTokenize(t *testing.T) {
 		{
 			Token{LabelToken, IGNORE, "ZZZ", 0, 3},
 		},
+		{
+			Token{InstructionToken, D, "LDUR", 0, 4},
+			Token{RegisterToken, IGNORE, "X1", 5, 7},
+			Token{CommaToken, IGNORE, ",", 7, 8},
+			Token{LeftBracketToken, IGNORE, "[", 9, 10},
+			Token{RegisterToken, IGNORE, "SP", 10, 12},
+			Token{CommaToken, IGNORE, ",", 12, 13},
+			Token{NumberToken, IGNORE, "#-8", 14, 17},
+			Token{RightBracketToken, IGNORE, "]", 17, 18},
+		},
+		{
+			Token{InstructionToken, R, "FADDS", 0, 5},
+			Token{RegisterToken, IGNORE, "S0", 6, 8},
+			Token{CommaToken, IGNORE, ",", 8, 9},
+			Token{RegisterToken, IGNORE, "S1", 10, 12},
+			Token{CommaToken, IGNORE, ",", 12, 13},
+			Token{RegisterToken, IGNORE, "S31", 14, 17},
+		},
+		{
+			Token{InstructionToken, D, "LDURD", 0, 5},
+			Token{RegisterToken, IGNORE, "D2", 6, 8},
+			Token{CommaToken, IGNORE, ",", 8, 9},
+			Token{LeftBracketToken, IGNORE, "[", 10, 11},
+			Token{RegisterToken, IGNORE, "X1", 11, 13},
+			Token{CommaToken, IGNORE, ",", 13, 14},
+			Token{NumberToken, IGNORE, "#8", 15, 17},
+			Token{RightBracketToken, IGNORE, "]", 17, 18},
+		},
+		{
+			Token{InstructionToken, IW, "MOVZ", 0, 4},
+			Token{RegisterToken, IGNORE, "X1", 5, 7},
+			Token{CommaToken, IGNORE, ",", 7, 8},
+			Token{NumberToken, IGNORE, "#255", 9, 13},
+			Token{CommaToken, IGNORE, ",", 13, 14},
+			Token{ShiftToken, IGNORE, "LSL", 15, 18},
+			Token{NumberToken, IGNORE, "#16", 19, 22},
+		},
+		{
+			Token{InstructionToken, CB, "CBZ", 1, 4},
+			Token{RegisterToken, IGNORE, "X1", 5, 7},
+			Token{CommaToken, IGNORE, ",", 7, 8},
+			Token{LabelToken, IGNORE, "top", 9, 12},
+		},
+		{
+			Token{LabelToken, IGNORE, "S1", 0, 2},
+			Token{ColonToken, IGNORE, ":", 2, 3},
+			Token{InstructionToken, B, "B", 4, 5},
+			Token{LabelToken, IGNORE, "S1", 6, 8},
+		},
+		{
+			Token{InstructionToken, CB, "CBZ", 0, 3},
+			Token{RegisterToken, IGNORE, "X1", 4, 6},
+			Token{CommaToken, IGNORE, ",", 6, 7},
+			Token{LabelToken, IGNORE, "D2", 8, 10},
+		},
 	}
 
 	for i, in := range inputs {
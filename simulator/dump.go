@@ -0,0 +1,62 @@
+package simulator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// formatRegister formats the value of an integer register for PRNT and DUMP.
+func formatRegister(name string, value uint64) string {
+	return fmt.Sprintf("%-4s 0x%016X (%d)", name+":", value, int64(value))
+}
+
+// Flag returns 1 if a condition flag is set and 0 otherwise, the way DUMP
+// shows it.
+func Flag(set bool) int {
+	if set {
+		return 1
+	}
+	return 0
+}
+
+// Dump writes the registers, flags and every 16-byte row of memory that is
+// not all zero.
+func (m *Machine) Dump(w io.Writer) {
+	fmt.Fprintln(w, "Registers:")
+	for i := 0; i < 31; i++ {
+		fmt.Fprintf(w, "  %s\n", formatRegister(fmt.Sprintf("X%d", i), m.X[i]))
+	}
+	for i, bits := range m.F {
+		if bits != 0 {
+			fmt.Fprintf(w, "  %-4s %g (S%d: %g)\n", fmt.Sprintf("D%d:", i), m.Double(i), i, m.Single(i))
+		}
+	}
+	fmt.Fprintf(w, "Flags: N=%d Z=%d C=%d V=%d\n", Flag(m.N), Flag(m.Z), Flag(m.C), Flag(m.V))
+
+	fmt.Fprintln(w, "Memory:")
+	for row := 0; row < len(m.Memory); row += 16 {
+		end := row + 16
+		if end > len(m.Memory) {
+			end = len(m.Memory)
+		}
+		bytes := m.Memory[row:end]
+		if allZero(bytes) {
+			continue
+		}
+		hex := make([]string, len(bytes))
+		for i, b := range bytes {
+			hex[i] = fmt.Sprintf("%02X", b)
+		}
+		fmt.Fprintf(w, "  %08X: %s\n", row, strings.Join(hex, " "))
+	}
+}
+
+func allZero(bytes []byte) bool {
+	for _, b := range bytes {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,316 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+
+	"server/isa"
+)
+
+// reg returns the value of an integer register.
+func (m *Machine) reg(n uint32) uint64 {
+	if n == isa.XZR {
+		return 0
+	}
+	return m.X[n]
+}
+
+// setReg writes an integer register. Writes to XZR are discarded.
+func (m *Machine) setReg(n uint32, value uint64) {
+	if n != isa.XZR {
+		m.X[n] = value
+	}
+}
+
+// Single returns the value of a single precision register.
+func (m *Machine) Single(n int) float32 {
+	return math.Float32frombits(uint32(m.F[n]))
+}
+
+// Double returns the value of a double precision register.
+func (m *Machine) Double(n int) float64 {
+	return math.Float64frombits(m.F[n])
+}
+
+func (m *Machine) setSingle(n uint32, value float32) {
+	m.F[n] = uint64(math.Float32bits(value))
+}
+
+func (m *Machine) setDouble(n uint32, value float64) {
+	m.F[n] = math.Float64bits(value)
+}
+
+// setFlags sets N and Z from a result, and C and V as given.
+func (m *Machine) setFlags(result uint64, carry, overflow bool) {
+	m.N = int64(result) < 0
+	m.Z = result == 0
+	m.C = carry
+	m.V = overflow
+}
+
+func (m *Machine) add(a, b uint64, setFlags bool) uint64 {
+	result, carry := bits.Add64(a, b, 0)
+	if setFlags {
+		m.setFlags(result, carry != 0, (^(a^b)&(a^result))>>63 != 0)
+	}
+	return result
+}
+
+func (m *Machine) sub(a, b uint64, setFlags bool) uint64 {
+	result, borrow := bits.Sub64(a, b, 0)
+	if setFlags {
+		m.setFlags(result, borrow == 0, ((a^b)&(a^result))>>63 != 0)
+	}
+	return result
+}
+
+// compare sets the flags from a floating-point comparison the way FCMP does.
+func (m *Machine) compare(a, b float64) {
+	switch {
+	case a == b:
+		m.N, m.Z, m.C, m.V = false, true, true, false
+	case a < b:
+		m.N, m.Z, m.C, m.V = true, false, false, false
+	case a > b:
+		m.N, m.Z, m.C, m.V = false, false, true, false
+	default:
+		m.N, m.Z, m.C, m.V = false, false, true, true
+	}
+}
+
+// condition reports whether the flags satisfy a B.cond condition.
+func (m *Machine) condition(suffix string) bool {
+	switch suffix {
+	case "EQ":
+		return m.Z
+	case "NE":
+		return !m.Z
+	case "HS":
+		return m.C
+	case "LO":
+		return !m.C
+	case "MI":
+		return m.N
+	case "PL":
+		return !m.N
+	case "VS":
+		return m.V
+	case "VC":
+		return !m.V
+	case "HI":
+		return m.C && !m.Z
+	case "LS":
+		return !(m.C && !m.Z)
+	case "GE":
+		return m.N == m.V
+	case "LT":
+		return m.N != m.V
+	case "GT":
+		return !m.Z && m.N == m.V
+	case "LE":
+		return !(!m.Z && m.N == m.V)
+	}
+	return false
+}
+
+// signExtend interprets the low bits of value as a two's complement number.
+func signExtend(value uint32, width int) int64 {
+	value &= 1<<width - 1
+	if value&(1<<(width-1)) != 0 {
+		return int64(value) - 1<<width
+	}
+	return int64(value)
+}
+
+// execute runs a single instruction, returning the address to continue at if
+// it branches.
+func (m *Machine) execute(info *isa.Instruction, word uint32) (*uint64, error) {
+	rd := word & 31
+	rn := (word >> 5) & 31
+	rm := (word >> 16) & 31
+	shamt := (word >> 10) & 63
+	branch := func(offset int64) *uint64 {
+		target := uint64(int64(m.PC) + 4*offset)
+		return &target
+	}
+
+	switch info.Format {
+	case isa.I:
+		a, immediate := m.reg(rn), uint64((word>>10)&(1<<12-1))
+		switch info.Mnemonic {
+		case "ADDI", "ADDIS":
+			m.setReg(rd, m.add(a, immediate, info.Mnemonic == "ADDIS"))
+		case "SUBI", "SUBIS":
+			m.setReg(rd, m.sub(a, immediate, info.Mnemonic == "SUBIS"))
+		case "ANDI", "ANDIS":
+			result := a & immediate
+			if info.Mnemonic == "ANDIS" {
+				m.setFlags(result, false, false)
+			}
+			m.setReg(rd, result)
+		case "ORRI":
+			m.setReg(rd, a|immediate)
+		case "EORI":
+			m.setReg(rd, a^immediate)
+		}
+	case isa.D:
+		return nil, m.transfer(info.Mnemonic, rd, m.reg(rn)+uint64(signExtend(word>>12, 9)))
+	case isa.B:
+		if info.Mnemonic == "BL" {
+			m.setReg(isa.LR, m.PC+4)
+		}
+		return branch(signExtend(word, 26)), nil
+	case isa.CB:
+		offset := signExtend(word>>5, 19)
+		taken := false
+		switch info.Mnemonic {
+		case "CBZ":
+			taken = m.reg(rd) == 0
+		case "CBNZ":
+			taken = m.reg(rd) != 0
+		default:
+			taken = m.condition(strings.TrimPrefix(info.Mnemonic, "B."))
+		}
+		if taken {
+			return branch(offset), nil
+		}
+	case isa.IW:
+		immediate := uint64((word >> 5) & (1<<16 - 1))
+		shift := 16 * ((word >> 21) & 3)
+		if info.Mnemonic == "MOVZ" {
+			m.setReg(rd, immediate<<shift)
+		} else {
+			m.setReg(rd, m.reg(rd)&^(0xFFFF<<shift)|immediate<<shift)
+		}
+	case isa.R:
+		return m.executeR(info, rd, rn, rm, shamt)
+	}
+	return nil, nil
+}
+
+func (m *Machine) executeR(info *isa.Instruction, rd, rn, rm, shamt uint32) (*uint64, error) {
+	a, b := m.reg(rn), m.reg(rm)
+	switch info.Mnemonic {
+	case "ADD", "ADDS":
+		m.setReg(rd, m.add(a, b, info.Mnemonic == "ADDS"))
+	case "SUB", "SUBS":
+		m.setReg(rd, m.sub(a, b, info.Mnemonic == "SUBS"))
+	case "AND", "ANDS":
+		result := a & b
+		if info.Mnemonic == "ANDS" {
+			m.setFlags(result, false, false)
+		}
+		m.setReg(rd, result)
+	case "ORR":
+		m.setReg(rd, a|b)
+	case "EOR":
+		m.setReg(rd, a^b)
+	case "MUL":
+		m.setReg(rd, a*b)
+	case "UMULH":
+		high, _ := bits.Mul64(a, b)
+		m.setReg(rd, high)
+	case "SMULH":
+		high, _ := bits.Mul64(a, b)
+		// correct the unsigned product for negative operands
+		if int64(a) < 0 {
+			high -= b
+		}
+		if int64(b) < 0 {
+			high -= a
+		}
+		m.setReg(rd, high)
+	case "SDIV":
+		if b == 0 {
+			m.setReg(rd, 0)
+		} else if int64(a) == math.MinInt64 && int64(b) == -1 {
+			m.setReg(rd, a)
+		} else {
+			m.setReg(rd, uint64(int64(a)/int64(b)))
+		}
+	case "UDIV":
+		if b == 0 {
+			m.setReg(rd, 0)
+		} else {
+			m.setReg(rd, a/b)
+		}
+	case "LSL":
+		m.setReg(rd, a<<shamt)
+	case "LSR":
+		m.setReg(rd, a>>shamt)
+	case "BR", "RET":
+		target := a
+		return &target, nil
+	case "BLR":
+		target := a
+		m.setReg(isa.LR, m.PC+4)
+		return &target, nil
+	case "FADDS":
+		m.setSingle(rd, m.Single(int(rn))+m.Single(int(rm)))
+	case "FSUBS":
+		m.setSingle(rd, m.Single(int(rn))-m.Single(int(rm)))
+	case "FMULS":
+		m.setSingle(rd, m.Single(int(rn))*m.Single(int(rm)))
+	case "FDIVS":
+		m.setSingle(rd, m.Single(int(rn))/m.Single(int(rm)))
+	case "FCMPS":
+		m.compare(float64(m.Single(int(rn))), float64(m.Single(int(rm))))
+	case "FADDD":
+		m.setDouble(rd, m.Double(int(rn))+m.Double(int(rm)))
+	case "FSUBD":
+		m.setDouble(rd, m.Double(int(rn))-m.Double(int(rm)))
+	case "FMULD":
+		m.setDouble(rd, m.Double(int(rn))*m.Double(int(rm)))
+	case "FDIVD":
+		m.setDouble(rd, m.Double(int(rn))/m.Double(int(rm)))
+	case "FCMPD":
+		m.compare(m.Double(int(rn)), m.Double(int(rm)))
+	case "PRNT":
+		fmt.Fprintf(m.output, "%s\n", formatRegister(fmt.Sprintf("X%d", rd), m.reg(rd)))
+	case "PRNL":
+		fmt.Fprintln(m.output)
+	case "DUMP":
+		m.Dump(m.output)
+	case "HALT":
+		m.Dump(m.output)
+		m.Halted = true
+	default:
+		return nil, errors.New("Instruction " + info.Mnemonic + " cannot be executed.")
+	}
+	return nil, nil
+}
+
+// loadSizes and storeSizes give the number of bytes each load and store
+// transfers.
+var (
+	loadSizes  = map[string]int{"LDUR": 8, "LDXR": 8, "LDURB": 1, "LDURH": 2, "LDURSW": 4, "LDURS": 4, "LDURD": 8}
+	storeSizes = map[string]int{"STUR": 8, "STXR": 8, "STURB": 1, "STURH": 2, "STURW": 4, "STURS": 4, "STURD": 8}
+)
+
+// transfer performs a load or store between register rt and memory.
+func (m *Machine) transfer(mnemonic string, rt uint32, address uint64) error {
+	if size, ok := storeSizes[mnemonic]; ok {
+		value := m.reg(rt)
+		if mnemonic == "STURS" || mnemonic == "STURD" {
+			value = m.F[rt]
+		}
+		return m.Store(address, size, value)
+	}
+
+	value, err := m.Load(address, loadSizes[mnemonic])
+	if err != nil {
+		return err
+	}
+	switch mnemonic {
+	case "LDURSW":
+		m.setReg(rt, uint64(int64(int32(value))))
+	case "LDURS", "LDURD":
+		m.F[rt] = value
+	default:
+		m.setReg(rt, value)
+	}
+	return nil
+}
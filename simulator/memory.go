@@ -0,0 +1,34 @@
+package simulator
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Load reads a little-endian value of size bytes from memory.
+func (m *Machine) Load(address uint64, size int) (uint64, error) {
+	if err := m.check(address, size); err != nil {
+		return 0, err
+	}
+	var buf [8]byte
+	copy(buf[:], m.Memory[address:address+uint64(size)])
+	return binary.LittleEndian.Uint64(buf[:]), nil
+}
+
+// Store writes the low size bytes of a value to memory in little-endian order.
+func (m *Machine) Store(address uint64, size int, value uint64) error {
+	if err := m.check(address, size); err != nil {
+		return err
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], value)
+	copy(m.Memory[address:], buf[:size])
+	return nil
+}
+
+func (m *Machine) check(address uint64, size int) error {
+	if address > uint64(len(m.Memory)) || uint64(len(m.Memory))-address < uint64(size) {
+		return fmt.Errorf("Memory access of %d bytes at address %d is out of bounds.", size, int64(address))
+	}
+	return nil
+}
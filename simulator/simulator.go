@@ -0,0 +1,150 @@
+// Package simulator executes assembled LEGv8 programs one instruction at a
+// time, modelling the registers, condition flags and memory of the machine.
+package simulator
+
+import (
+	"fmt"
+	"io"
+
+	"server/assembler"
+	"server/ast"
+	"server/isa"
+)
+
+const (
+	// MemorySize is the number of bytes of memory. The stack starts at the
+	// top of memory and grows down.
+	MemorySize = 1 << 16
+
+	// StepLimit is the number of instructions Run executes before deciding
+	// the program will never halt.
+	StepLimit = 1_000_000
+)
+
+// Error is a problem that stops the program, such as an out of bounds memory
+// access.
+type Error struct {
+	// Line is the zero-based source line of the instruction that failed.
+	Line    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Line+1, e.Message)
+}
+
+// Machine is the state of a running program.
+type Machine struct {
+	// X holds the integer registers. X[31] is XZR, which always reads as zero.
+	X [32]uint64
+
+	// F holds the bits of the double precision registers. Each single
+	// precision register is the low 32 bits of the double of the same number.
+	F [32]uint64
+
+	N, Z, C, V bool
+
+	// PC is the byte address of the next instruction.
+	PC     uint64
+	Memory []byte
+
+	// Halted is set once the program executes HALT or runs past its last
+	// instruction.
+	Halted bool
+
+	output io.Writer
+	words  []assembler.Word
+}
+
+// New assembles a program and prepares it to run, with output from PRNT,
+// PRNL and DUMP written to output.
+func New(program *ast.Program, output io.Writer) (*Machine, []*assembler.Error) {
+	assembly, errs := assembler.Assemble(program)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	m := &Machine{
+		Memory: make([]byte, MemorySize),
+		output: output,
+		words:  assembly.Words,
+	}
+	m.X[isa.SP] = MemorySize
+	m.X[isa.FP] = MemorySize
+	return m, nil
+}
+
+// Line returns the zero-based source line of the next instruction, or -1 if
+// the program has halted.
+func (m *Machine) Line() int {
+	if m.Halted {
+		return -1
+	}
+	return m.LineAt(m.PC)
+}
+
+// LineAt returns the zero-based source line of the instruction at an
+// address, or -1 if there is none.
+func (m *Machine) LineAt(address uint64) int {
+	if address%4 != 0 || address/4 >= uint64(len(m.words)) {
+		return -1
+	}
+	return m.words[address/4].Line
+}
+
+// Next returns the instruction that Step will execute next.
+func (m *Machine) Next() (*isa.Instruction, bool) {
+	if m.Halted || m.PC%4 != 0 || m.PC/4 >= uint64(len(m.words)) {
+		return nil, false
+	}
+	return isa.Decode(m.words[m.PC/4].Encoding)
+}
+
+// Step executes a single instruction.
+func (m *Machine) Step() error {
+	if m.Halted {
+		return nil
+	}
+	index := m.PC / 4
+	if index == uint64(len(m.words)) {
+		m.Halted = true
+		return nil
+	}
+	if m.PC%4 != 0 || index > uint64(len(m.words)) {
+		line := -1
+		if len(m.words) > 0 {
+			line = m.words[len(m.words)-1].Line
+		}
+		return &Error{line, fmt.Sprintf("Branch to address %d is outside the program.", m.PC)}
+	}
+
+	word := m.words[index]
+	info, ok := isa.Decode(word.Encoding)
+	if !ok {
+		return &Error{word.Line, fmt.Sprintf("Unknown instruction encoding %08X.", word.Encoding)}
+	}
+
+	next := m.PC + 4
+	target, err := m.execute(info, word.Encoding)
+	if err != nil {
+		return &Error{word.Line, err.Error()}
+	}
+	if target != nil {
+		next = *target
+	}
+	m.PC = next
+	return nil
+}
+
+// Run executes instructions until the program halts.
+func (m *Machine) Run() error {
+	for steps := 0; !m.Halted; steps++ {
+		if steps == StepLimit {
+			return &Error{m.Line(), fmt.Sprintf("Program did not halt after %d instructions.", StepLimit)}
+		}
+		if err := m.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
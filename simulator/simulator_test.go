@@ -0,0 +1,153 @@
+package simulator_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"server/parser"
+	"server/simulator"
+)
+
+func run(t *testing.T, source string) (*simulator.Machine, string, error) {
+	t.Helper()
+	var output bytes.Buffer
+	m, errs := simulator.New(parser.ParseProgram(source), &output)
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected assembler errors %v. Input: %q", errs, source)
+	}
+	err := m.Run()
+	return m, output.String(), err
+}
+
+func TestRegisters(t *testing.T) {
+	inputs := []struct {
+		source   string
+		register int
+		expected uint64
+	}{
+		{"ADDI X1, XZR, #5\nADDI X2, XZR, #7\nADD X3, X1, X2", 3, 12},
+		{"ADDI X1, XZR, #5\nSUBI X2, X1, #7", 2, 0xFFFFFFFFFFFFFFFE},
+		{"ADDI XZR, XZR, #5\nADD X1, XZR, XZR", 1, 0},
+		{"MOVZ X1, #4660, LSL #16\nMOVK X1, #65535, LSL #0", 1, 0x1234FFFF},
+		{"MOV X1, SP", 1, simulator.MemorySize},
+		{"ADR X1, end\nADDI X2, XZR, #1\nend:", 1, 8},
+		{"ADDI X1, XZR, #6\nADDI X2, XZR, #7\nMNEG X3, X1, X2", 3, 0xFFFFFFFFFFFFFFD6},
+		{"ADDI X1, XZR, #6\nADDI X2, XZR, #7\nSMULL X3, X1, X2", 3, 42},
+		{"MNEG X1, X1, X1\nBL five\nB end\nfive:\nADDI X0, XZR, #5\nRET\nend:", 0, 5},
+		{"ADDI X1, XZR, #6\nLSL X2, X1, #4\nLSR X3, X2, #2", 3, 24},
+		{"ADDI X1, XZR, #6\nSUB X1, XZR, X1\nADDI X2, XZR, #4\nSDIV X3, X1, X2", 3, 0xFFFFFFFFFFFFFFFF},
+		{"ADDI X1, XZR, #6\nUDIV X3, X1, XZR", 3, 0},
+		{"ADDI X1, XZR, #1\nSUB X1, XZR, X1\nADDI X2, XZR, #3\nSMULH X3, X1, X2", 3, 0xFFFFFFFFFFFFFFFF},
+		{"ADDI X1, XZR, #1\nSUB X1, XZR, X1\nADDI X2, XZR, #3\nUMULH X3, X1, X2", 3, 2},
+		{"ADDI X1, XZR, #12\nANDI X2, X1, #10\nORRI X3, X2, #1\nEORI X4, X3, #15", 4, 6},
+		{"ADDI X0, XZR, #0\nADDI X1, XZR, #10\nloop:\nADD X0, X0, X1\nSUBIS X1, X1, #1\nB.NE loop", 0, 55},
+		{"ADDI X0, XZR, #3\nloop:\nSUBI X0, X0, #1\nCBNZ X0, loop\nADDI X1, XZR, #9", 1, 9},
+		{"ADDI X0, XZR, #2\nBL double\nB end\ndouble:\nADD X0, X0, X0\nBR LR\nend:", 0, 4},
+		{"ADDI X0, XZR, #2\nADDI X9, XZR, #16\nBLR X9\nB end\ndouble:\nADD X0, X0, X0\nRET\nend:", 0, 4},
+		{"ADDI X0, XZR, #1\nHALT\nADDI X0, XZR, #2", 0, 1},
+		{"MOVZ X1, #4660, LSL #0\nSTUR X1, [SP, #-8]\nLDURB X2, [SP, #-8]", 2, 0x34},
+		{"MOVZ X1, #32768, LSL #16\nSTURW X1, [SP, #-8]\nLDURSW X2, [SP, #-8]", 2, 0xFFFFFFFF80000000},
+		{"SUBI SP, SP, #16\nADDI X1, XZR, #42\nSTUR X1, [SP, #0]\nLDUR X2, [SP, #0]", 2, 42},
+	}
+
+	for _, in := range inputs {
+		m, _, err := run(t, in.source)
+		if err != nil {
+			t.Errorf("Unexpected error %v. Input: %q", err, in.source)
+			continue
+		}
+		if m.X[in.register] != in.expected {
+			t.Errorf("Expected X%d to be %X, got %X. Input: %q", in.register, in.expected, m.X[in.register], in.source)
+		}
+	}
+}
+
+func TestFlags(t *testing.T) {
+	inputs := []struct {
+		source     string
+		n, z, c, v bool
+	}{
+		{"ADDI X1, XZR, #3\nSUBS X2, X1, X1", false, true, true, false},
+		{"ADDI X1, XZR, #3\nCMPI X1, #4", true, false, false, false},
+		{"ADDI X1, XZR, #3\nCMPI X1, #2", false, false, true, false},
+		{"MOVZ X1, #32767, LSL #48\nADDS X2, X1, X1", true, false, false, true},
+		{"ADDI X1, XZR, #1\nSUB X1, XZR, X1\nADDS X2, X1, X1", true, false, true, false},
+		{"ADDI X1, XZR, #12\nANDIS X2, X1, #3", false, true, false, false},
+		{"MOVZ X1, #16368, LSL #48\nSTUR X1, [SP, #-8]\nLDURD D1, [SP, #-8]\nFADDD D2, D1, D1\nFCMPD D2, D1", false, false, true, false},
+		{"MOVZ X1, #16256, LSL #16\nSTURW X1, [SP, #-4]\nLDURS S1, [SP, #-4]\nFCMPS S1, S1", false, true, true, false},
+	}
+
+	for _, in := range inputs {
+		m, _, err := run(t, in.source)
+		if err != nil {
+			t.Errorf("Unexpected error %v. Input: %q", err, in.source)
+			continue
+		}
+		if m.N != in.n || m.Z != in.z || m.C != in.c || m.V != in.v {
+			t.Errorf("Expected NZCV %v %v %v %v, got %v %v %v %v. Input: %q", in.n, in.z, in.c, in.v, m.N, m.Z, m.C, m.V, in.source)
+		}
+	}
+}
+
+func TestFloatingPoint(t *testing.T) {
+	source := "MOVZ X1, #16376, LSL #48\nSTUR X1, [SP, #-8]\nLDURD D1, [SP, #-8]\nFMULD D2, D1, D1\nFADDS S3, S3, S3"
+	m, _, err := run(t, source)
+	if err != nil {
+		t.Fatalf("Unexpected error %v.", err)
+	}
+	if m.Double(2) != 2.25 {
+		t.Errorf("Expected D2 to be 2.25, got %g.", m.Double(2))
+	}
+}
+
+func TestOutput(t *testing.T) {
+	_, output, err := run(t, "ADDI X9, XZR, #42\nPRNT X9\nPRNL\nDUMP")
+	if err != nil {
+		t.Fatalf("Unexpected error %v.", err)
+	}
+	if !strings.HasPrefix(output, "X9:  0x000000000000002A (42)\n\nRegisters:\n") {
+		t.Errorf("Incorrect output %q.", output)
+	}
+	if !strings.Contains(output, "Flags: N=0 Z=0 C=0 V=0\n") {
+		t.Errorf("Expected flags in output %q.", output)
+	}
+}
+
+func TestStep(t *testing.T) {
+	var output bytes.Buffer
+	m, _ := simulator.New(parser.ParseProgram("// start\nADDI X0, XZR, #1\n\nADDI X0, X0, #1"), &output)
+
+	lines := []int{}
+	for !m.Halted {
+		lines = append(lines, m.Line())
+		if err := m.Step(); err != nil {
+			t.Fatalf("Unexpected error %v.", err)
+		}
+	}
+	if len(lines) != 3 || lines[0] != 1 || lines[1] != 3 || lines[2] != -1 {
+		t.Errorf("Expected lines [1 3 -1], got %v.", lines)
+	}
+	if m.X[0] != 2 {
+		t.Errorf("Expected X0 to be 2, got %d.", m.X[0])
+	}
+}
+
+func TestErrors(t *testing.T) {
+	inputs := []struct {
+		source  string
+		message string
+	}{
+		{"ADDI X0, XZR, #1\nLDUR X1, [XZR, #-8]", "2: Memory access of 8 bytes at address -8 is out of bounds."},
+		{"STUR X1, [SP, #0]", "1: Memory access of 8 bytes at address 65536 is out of bounds."},
+		{"ADDI X0, XZR, #2\nBR X0", "2: Branch to address 2 is outside the program."},
+		{"loop:\nB loop", "2: Program did not halt after 1000000 instructions."},
+	}
+
+	for _, in := range inputs {
+		_, _, err := run(t, in.source)
+		if err == nil || err.Error() != in.message {
+			t.Errorf("Expected error %q, got %v. Input: %q", in.message, err, in.source)
+		}
+	}
+}